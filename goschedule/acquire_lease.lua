@@ -0,0 +1,18 @@
+local key = KEYS[1]
+local owner = ARGV[1]
+local tick = ARGV[2]
+local expiresAt = ARGV[3]
+local now = tonumber(ARGV[4])
+
+local prev = redis.call('HMGET', key, 'owner', 'tick', 'expires_at')
+local prevOwner = prev[1] or ''
+local prevTick = prev[2] or '0'
+local prevExpiresAt = prev[3] or '0'
+
+if prev[3] and tonumber(prev[3]) > now then
+  return {0, prevOwner, prevTick, prevExpiresAt}
+end
+
+redis.call('HSET', key, 'owner', owner, 'tick', tick, 'expires_at', expiresAt)
+
+return {1, prevOwner, prevTick, prevExpiresAt}
@@ -0,0 +1,123 @@
+package goschedule
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/zeromicro/go-zero/core/logx"
+	"github.com/zeromicro/go-zero/rest"
+	"github.com/zeromicro/go-zero/rest/httpx"
+)
+
+// AdminRoutes returns the admin endpoints of the Scheduler, to be mounted on a go-zero server, e.g.
+//
+//	server.AddRoutes(goschedule.AdminRoutes(s, goschedule.AdminBearerToken(token)), rest.WithPrefix("/admin/jobs"))
+//
+// The endpoints are:
+//
+//	GET  /             lists the jobs
+//	POST /:id/pause    pauses the job on every node
+//	POST /:id/resume   resumes the job
+//	POST /:id/trigger  runs the job once on this node
+//
+// Every request passes through authorize, which should reject the callers who are not allowed.
+// authorize can not be nil.
+func AdminRoutes(s *Scheduler, authorize rest.Middleware) []rest.Route {
+	if authorize == nil {
+		logx.Must(errors.New("goschedule: the admin routes require an authorize middleware"))
+	}
+
+	h := adminHandler{s}
+	return []rest.Route{
+		{Method: http.MethodGet, Path: "/", Handler: authorize(h.list)},
+		{Method: http.MethodPost, Path: "/:id/pause", Handler: authorize(h.pause)},
+		{Method: http.MethodPost, Path: "/:id/resume", Handler: authorize(h.resume)},
+		{Method: http.MethodPost, Path: "/:id/trigger", Handler: authorize(h.trigger)},
+	}
+}
+
+// AdminBearerToken returns a middleware which only lets the requests with the header
+// "Authorization: Bearer <token>" pass. The others are answered with 401 Unauthorized.
+func AdminBearerToken(token string) rest.Middleware {
+	if token == "" {
+		logx.Must(errors.New("goschedule: the admin token can not be empty"))
+	}
+
+	expected := []byte("Bearer " + token)
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			actual := []byte(r.Header.Get("Authorization"))
+			if subtle.ConstantTimeCompare(actual, expected) != 1 {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+				return
+			}
+			next(w, r)
+		}
+	}
+}
+
+type adminHandler struct {
+	s *Scheduler
+}
+
+type adminJobRequest struct {
+	ID string `path:"id"`
+}
+
+type adminResponse struct {
+	Error string `json:"error,omitempty"`
+}
+
+func (h adminHandler) list(w http.ResponseWriter, r *http.Request) {
+	jobs, err := h.s.Jobs(r.Context())
+	if err != nil {
+		writeAdminError(w, r, err)
+		return
+	}
+	httpx.OkJsonCtx(r.Context(), w, jobs)
+}
+
+func (h adminHandler) pause(w http.ResponseWriter, r *http.Request) {
+	h.handleJob(w, r, h.s.Pause, http.StatusOK)
+}
+
+func (h adminHandler) resume(w http.ResponseWriter, r *http.Request) {
+	h.handleJob(w, r, h.s.Resume, http.StatusOK)
+}
+
+func (h adminHandler) trigger(w http.ResponseWriter, r *http.Request) {
+	h.handleJob(w, r, h.s.Trigger, http.StatusAccepted)
+}
+
+func (h adminHandler) handleJob(w http.ResponseWriter, r *http.Request,
+	fn func(ctx context.Context, jobID string) error, code int) {
+	var req adminJobRequest
+	if err := httpx.ParsePath(r, &req); err != nil {
+		httpx.WriteJsonCtx(r.Context(), w, http.StatusBadRequest, adminResponse{Error: err.Error()})
+		return
+	}
+	if err := fn(r.Context(), req.ID); err != nil {
+		writeAdminError(w, r, err)
+		return
+	}
+	httpx.WriteJsonCtx(r.Context(), w, code, adminResponse{})
+}
+
+func writeAdminError(w http.ResponseWriter, r *http.Request, err error) {
+	code := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, ErrJobNotFound):
+		code = http.StatusNotFound
+	case errors.Is(err, ErrJobTriggered), errors.Is(err, ErrJobRunning):
+		code = http.StatusConflict
+	case errors.Is(err, ErrConcurrencyLimit):
+		code = http.StatusServiceUnavailable
+	default:
+		logx.WithContext(r.Context()).Errorf("Can not handle admin request %s %s: %v", r.Method, r.URL.Path, err)
+	}
+	httpx.WriteJsonCtx(r.Context(), w, code, adminResponse{Error: strings.TrimPrefix(err.Error(), "goschedule: ")})
+}
@@ -0,0 +1,190 @@
+package goschedule
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+	"github.com/stretchr/testify/assert"
+	"github.com/zeromicro/go-zero/core/jsonx"
+	"github.com/zeromicro/go-zero/rest/httpx"
+	"github.com/zeromicro/go-zero/rest/router"
+)
+
+func newTestAdminRouter(t *testing.T, s *Scheduler) httpx.Router {
+	rt := router.NewRouter()
+	for _, route := range AdminRoutes(s, AdminBearerToken("secret")) {
+		if err := rt.Handle(route.Method, strings.TrimSuffix("/admin/jobs"+route.Path, "/"), route.Handler); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return rt
+}
+
+func serveAdmin(rt httpx.Router, method, path string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, path, nil)
+	r.Header.Set("Authorization", "Bearer secret")
+	w := httptest.NewRecorder()
+	rt.ServeHTTP(w, r)
+	return w
+}
+
+func TestAdminUnauthorized(t *testing.T) {
+	rt := newTestAdminRouter(t, newTestScheduler(t))
+
+	r := httptest.NewRequest(http.MethodGet, "/admin/jobs", nil)
+	r.Header.Set("Authorization", "Bearer wrong")
+	w := httptest.NewRecorder()
+	rt.ServeHTTP(w, r)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestAdminListAndPause(t *testing.T) {
+	clock := clockwork.NewFakeClockAt(testStart)
+	syncMgr := NewSynchronizationManagerMemory()
+	var done atomic.Int32
+	s := newTestScheduler(t, WithClock(clock), WithSynchronizationManager(syncMgr),
+		WithRunRecorder(NewRunRecorderMemory(0)), WithHooks(countTicks(&done)))
+	// Another node of the cluster, which is paused as well.
+	other := newTestScheduler(t, WithClock(clock), WithSynchronizationManager(syncMgr), WithNodeID("other"),
+		WithHooks(countTicks(&done)))
+	var runs atomic.Int32
+	for _, s := range []*Scheduler{s, other} {
+		_, err := s.ScheduleSynchronizedJob("test-admin", "* * * * * *", func() { runs.Add(1) })
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	rt := newTestAdminRouter(t, s)
+
+	advance(t, clock, time.Second, &done, 2)
+	assert.Equal(t, int32(1), runs.Load())
+	w := serveAdmin(rt, http.MethodGet, "/admin/jobs")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var jobs []JobInfo
+	if assert.NoError(t, jsonx.Unmarshal(w.Body.Bytes(), &jobs)) && assert.Len(t, jobs, 1) {
+		assert.Equal(t, "test-admin", jobs[0].ID)
+		assert.Equal(t, "* * * * * *", jobs[0].Rule)
+		assert.Equal(t, testStart.Add(2*time.Second), jobs[0].NextRun.UTC())
+		assert.False(t, jobs[0].Paused)
+	}
+
+	w = serveAdmin(rt, http.MethodPost, "/admin/jobs/test-admin/pause")
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = serveAdmin(rt, http.MethodGet, "/admin/jobs")
+	jobs = nil
+	if assert.NoError(t, jsonx.Unmarshal(w.Body.Bytes(), &jobs)) && assert.Len(t, jobs, 1) {
+		assert.True(t, jobs[0].Paused)
+	}
+
+	advance(t, clock, time.Second, &done, 2)
+	advance(t, clock, time.Second, &done, 2)
+	assert.Equal(t, int32(1), runs.Load())
+
+	w = serveAdmin(rt, http.MethodPost, "/admin/jobs/test-admin/resume")
+	assert.Equal(t, http.StatusOK, w.Code)
+	advance(t, clock, time.Second, &done, 2)
+	assert.Equal(t, int32(2), runs.Load())
+
+	w = serveAdmin(rt, http.MethodPost, "/admin/jobs/unknown/pause")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestAdminTrigger(t *testing.T) {
+	syncMgr := NewSynchronizationManagerXorm(newTestEngine(t), "synchronization")
+	recorder := NewRunRecorderMemory(0)
+	runs := make(chan struct{}, 10)
+	var routers []httpx.Router
+	clock := clockwork.NewFakeClockAt(testStart)
+	for _, nodeID := range []string{"node-a", "node-b"} {
+		s := newTestScheduler(t, WithClock(clock), WithSynchronizationManager(syncMgr), WithRunRecorder(recorder),
+			WithNodeID(nodeID))
+		_, err := s.ScheduleSynchronizedJob("test-admin-trigger", yearlyRule, func() { runs <- struct{}{} })
+		if err != nil {
+			t.Fatal(err)
+		}
+		routers = append(routers, newTestAdminRouter(t, s))
+	}
+
+	// Triggers of the same second on different nodes run the job once.
+	w := serveAdmin(routers[0], http.MethodPost, "/admin/jobs/test-admin-trigger/trigger")
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	w = serveAdmin(routers[1], http.MethodPost, "/admin/jobs/test-admin-trigger/trigger")
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	select {
+	case <-runs:
+	case <-time.After(time.Second):
+		t.Fatal("the triggered job has not run")
+	}
+	assert.Eventually(t, func() bool {
+		records, _ := recorder.LastRuns(context.Background(), "test-admin-trigger", 10)
+		return len(records) == 1
+	}, time.Second, time.Millisecond)
+	assert.Empty(t, runs)
+
+	w = serveAdmin(routers[1], http.MethodGet, "/admin/jobs")
+	var jobs []JobInfo
+	if assert.NoError(t, jsonx.Unmarshal(w.Body.Bytes(), &jobs)) && assert.Len(t, jobs, 1) &&
+		assert.NotNil(t, jobs[0].LastRun) {
+		assert.Equal(t, "node-a", jobs[0].LastRun.NodeID)
+	}
+}
+
+func TestTriggerHoldsLockForRun(t *testing.T) {
+	clock := clockwork.NewFakeClockAt(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	syncMgr := NewSynchronizationManagerMemory()
+	release := make(chan struct{})
+	runs := make(chan struct{}, 10)
+	var schedulers []*Scheduler
+	for _, nodeID := range []string{"node-a", "node-b"} {
+		s := newTestScheduler(t, WithClock(clock), WithSynchronizationManager(syncMgr), WithNodeID(nodeID))
+		_, err := s.ScheduleSynchronizedJob("test-trigger-lock", yearlyRule, func() {
+			runs <- struct{}{}
+			<-release
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		schedulers = append(schedulers, s)
+	}
+
+	ctx := context.Background()
+	assert.NoError(t, schedulers[0].Trigger(ctx, "test-trigger-lock"))
+	<-runs
+	// The triggered run is still in progress seconds later.
+	clock.Advance(5 * time.Second)
+	assert.ErrorIs(t, schedulers[1].Trigger(ctx, "test-trigger-lock"), ErrJobTriggered)
+	assert.ErrorIs(t, schedulers[0].Trigger(ctx, "test-trigger-lock"), ErrJobTriggered)
+
+	close(release)
+	assert.Eventually(t, func() bool {
+		return schedulers[1].Trigger(ctx, "test-trigger-lock") == nil
+	}, time.Second, 10*time.Millisecond)
+	<-runs
+}
+
+func TestJobsNextRunCalendar(t *testing.T) {
+	// A Friday afternoon.
+	clock := clockwork.NewFakeClockAt(time.Date(2024, 10, 11, 15, 0, 0, 0, time.UTC))
+	s := newTestScheduler(t, WithClock(clock))
+	calendar, err := NewDateListCalendar(DateListConf{Weekdays: []string{"Saturday", "Sunday"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = s.ScheduleSynchronizedJob("test-next-run-calendar", "0 0 9 * * *", func() {},
+		WithTimeZone("UTC"), WithCalendar(calendar))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	jobs, err := s.Jobs(context.Background())
+	if assert.NoError(t, err) && assert.Len(t, jobs, 1) {
+		assert.Equal(t, time.Date(2024, 10, 14, 9, 0, 0, 0, time.UTC), jobs[0].NextRun.UTC())
+	}
+}
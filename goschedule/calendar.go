@@ -0,0 +1,161 @@
+package goschedule
+
+//lint:file-ignore SA5008 Use gozero config tags
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"github.com/zeromicro/go-zero/core/conf"
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+// Calendar excludes days from the schedule of jobs, e.g. public holidays.
+type Calendar interface {
+	// Excluded reports whether the day of t is excluded. t is in the time zone of the job.
+	Excluded(t time.Time) bool
+}
+
+// WithTimeZone sets the IANA time zone of the job, e.g. "Asia/Shanghai", in which its cron rule
+// and Calendar are evaluated. The default is the local time zone of the server.
+func WithTimeZone(name string) JobOption {
+	return func(options *jobOptions) {
+		options.timeZone = name
+	}
+}
+
+// WithCalendar suppresses the ticks of the job on the days excluded by calendar.
+// The suppressed ticks are neither run nor caught up by the MisfirePolicy.
+func WithCalendar(calendar Calendar) JobOption {
+	return func(options *jobOptions) {
+		options.calendar = calendar
+	}
+}
+
+// maxExcludedDays is how many days in a row calendarSchedule looks ahead for a tick.
+const maxExcludedDays = 10 * 366
+
+// calendarSchedule skips the ticks of schedule on the days excluded by calendar.
+type calendarSchedule struct {
+	schedule cron.Schedule
+	calendar Calendar
+	location *time.Location
+}
+
+func (s calendarSchedule) Next(t time.Time) time.Time {
+	for days := 0; days < maxExcludedDays; days++ {
+		t = s.schedule.Next(t)
+		if t.IsZero() {
+			return t
+		}
+		local := t.In(s.location)
+		if !s.calendar.Excluded(local) {
+			return t
+		}
+		// Skip the rest of the excluded day.
+		year, month, day := local.Date()
+		t = time.Date(year, month, day+1, 0, 0, 0, 0, s.location).Add(-time.Nanosecond)
+	}
+	return time.Time{}
+}
+
+// excluded reports whether the tick scheduled at t is on a day excluded by the Calendar of the job,
+// or repeats the wall clock time of a tick at fixed hours, which gocron runs twice when the clocks are turned back.
+func (j *synchronizedJob) excluded(t time.Time) bool {
+	if s, ok := j.schedule.(calendarSchedule); ok && s.calendar.Excluded(t.In(s.location)) {
+		logx.Infof("Synchronized job %q of %s is excluded by the calendar, skip it", j.id, t.Format(time.RFC3339))
+		return true
+	}
+	if j.parsed.dst && repeatsWallClock(t, j.parsed.location) {
+		logx.Infof("Synchronized job %q of %s repeats the wall clock time, skip it", j.id, t.Format(time.RFC3339))
+		return true
+	}
+	return false
+}
+
+// DateListConf is the configuration of a Calendar which excludes listed dates and days of the week.
+//
+//nolint:staticcheck
+type DateListConf struct {
+	// Dates are the excluded dates, e.g. 2024-10-01.
+	Dates []string `json:",optional"`
+	// Weekdays are the excluded days of the week, e.g. Saturday and Sunday.
+	Weekdays []string `json:",optional"`
+	// Workdays are the dates which are not excluded even if they are on excluded Weekdays.
+	Workdays []string `json:",optional"`
+}
+
+type dateListCalendar struct {
+	dates    map[string]bool
+	weekdays map[time.Weekday]bool
+	workdays map[string]bool
+}
+
+const dateLayout = "2006-01-02"
+
+var weekdays = map[string]time.Weekday{
+	"Sunday":    time.Sunday,
+	"Monday":    time.Monday,
+	"Tuesday":   time.Tuesday,
+	"Wednesday": time.Wednesday,
+	"Thursday":  time.Thursday,
+	"Friday":    time.Friday,
+	"Saturday":  time.Saturday,
+}
+
+// NewDateListCalendar returns a Calendar which excludes the dates and days of the week of c.
+func NewDateListCalendar(c DateListConf) (Calendar, error) {
+	calendar := new(dateListCalendar)
+	var err error
+	if calendar.dates, err = parseDates(c.Dates); err != nil {
+		return nil, err
+	}
+	if calendar.workdays, err = parseDates(c.Workdays); err != nil {
+		return nil, err
+	}
+	calendar.weekdays = make(map[time.Weekday]bool, len(c.Weekdays))
+	for _, name := range c.Weekdays {
+		weekday, ok := weekdays[name]
+		if !ok {
+			return nil, fmt.Errorf("goschedule: invalid weekday %q", name)
+		}
+		calendar.weekdays[weekday] = true
+	}
+	return calendar, nil
+}
+
+// LoadDateListCalendar loads a DateListConf from a go-zero config file, and returns its Calendar.
+func LoadDateListCalendar(file string) (Calendar, error) {
+	var c DateListConf
+	if err := conf.Load(file, &c); err != nil {
+		return nil, err
+	}
+	return NewDateListCalendar(c)
+}
+
+// MustLoadDateListCalendar is like LoadDateListCalendar but exits on error.
+func MustLoadDateListCalendar(file string) Calendar {
+	calendar, err := LoadDateListCalendar(file)
+	logx.Must(err)
+	return calendar
+}
+
+func parseDates(values []string) (map[string]bool, error) {
+	dates := make(map[string]bool, len(values))
+	for _, value := range values {
+		if _, err := time.Parse(dateLayout, value); err != nil {
+			return nil, err
+		}
+		dates[value] = true
+	}
+	return dates, nil
+}
+
+func (calendar *dateListCalendar) Excluded(t time.Time) bool {
+	date := t.Format(dateLayout)
+	if calendar.dates[date] {
+		return true
+	}
+	return calendar.weekdays[t.Weekday()] && !calendar.workdays[date]
+}
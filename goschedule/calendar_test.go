@@ -0,0 +1,179 @@
+package goschedule
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDateListCalendar(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "holidays.yaml")
+	err := os.WriteFile(file, []byte(`
+Dates:
+  - 2024-10-01
+Weekdays: [Saturday, Sunday]
+Workdays: [2024-10-12]
+`), 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	calendar, err := LoadDateListCalendar(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	day := func(date string) time.Time {
+		d, _ := time.Parse(dateLayout, date)
+		return d
+	}
+	assert.True(t, calendar.Excluded(day("2024-10-01")))
+	assert.False(t, calendar.Excluded(day("2024-10-02")))
+	assert.True(t, calendar.Excluded(day("2024-10-13")))  // Sunday
+	assert.False(t, calendar.Excluded(day("2024-10-12"))) // Saturday, but a workday
+
+	_, err = NewDateListCalendar(DateListConf{Dates: []string{"2024/10/01"}})
+	assert.Error(t, err)
+	_, err = NewDateListCalendar(DateListConf{Weekdays: []string{"Caturday"}})
+	assert.Error(t, err)
+}
+
+// runWithFakeClock schedules a job on a Scheduler with a fake clock starting at start,
+// advances the clock from tick to tick until end, and returns the scheduled times of the runs.
+func runWithFakeClock(t *testing.T, start, end time.Time, rule string, opts ...JobOption) []time.Time {
+	clock := clockwork.NewFakeClockAt(start)
+	syncMgr := NewSynchronizationManagerMemory()
+	s := newTestScheduler(t, WithClock(clock), WithSynchronizationManager(syncMgr))
+
+	var mu sync.Mutex
+	var runs []time.Time
+	var count int
+	_, err := s.ScheduleSynchronizedJobContext("test-fake-clock", rule, func(ctx context.Context) error {
+		mu.Lock()
+		defer mu.Unlock()
+		runs = append(runs, ScheduledTime(ctx))
+		return nil
+	}, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	nextRun := func() time.Time {
+		jobs, err := s.Jobs(context.Background())
+		if err != nil || len(jobs) != 1 {
+			t.Fatal(jobs, err)
+		}
+		return jobs[0].NextRun
+	}
+	// finished reports whether the tick has either run or been skipped.
+	finished := func(tick time.Time) bool {
+		mu.Lock()
+		defer mu.Unlock()
+		if slices.ContainsFunc(runs, tick.Equal) {
+			return true
+		}
+		skipped, ok := syncMgr.Get(skippedKey("test-fake-clock"))
+		return ok && skipped == tick.UnixMilli()
+	}
+	for next := nextRun(); !next.IsZero() && next.Before(end); count++ {
+		clock.Advance(next.Sub(clock.Now()))
+		tick := next
+		assert.Eventually(t, func() bool {
+			next = nextRun()
+			return next.After(tick) && finished(tick)
+		}, time.Second, time.Millisecond)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	t.Logf("%d ticks, %d runs", count, len(runs))
+	return runs
+}
+
+func formatTimes(times []time.Time, location *time.Location) []string {
+	formatted := make([]string, 0, len(times))
+	for _, t := range times {
+		formatted = append(formatted, t.In(location).Format(time.RFC3339))
+	}
+	return formatted
+}
+
+func TestScheduleTimeZone(t *testing.T) {
+	shanghai, err := time.LoadLocation("Asia/Shanghai")
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2024, 9, 30, 0, 0, 0, 0, time.UTC)
+	runs := runWithFakeClock(t, start, start.Add(48*time.Hour), "0 0 2 * * *", WithTimeZone("Asia/Shanghai"))
+	assert.Equal(t, []string{
+		"2024-10-01T02:00:00+08:00",
+		"2024-10-02T02:00:00+08:00",
+	}, formatTimes(runs, shanghai))
+
+	_, err = newTestScheduler(t).ScheduleSynchronizedJob("test-time-zone", "* * * * * *", func() {},
+		WithTimeZone("Mars/Olympus_Mons"))
+	assert.Error(t, err)
+}
+
+func TestScheduleCalendar(t *testing.T) {
+	shanghai, err := time.LoadLocation("Asia/Shanghai")
+	if err != nil {
+		t.Fatal(err)
+	}
+	calendar, err := NewDateListCalendar(DateListConf{
+		Dates:    []string{"2024-10-01", "2024-10-02", "2024-10-03", "2024-10-04", "2024-10-07"},
+		Weekdays: []string{"Saturday", "Sunday"},
+		Workdays: []string{"2024-10-12"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Date(2024, 9, 30, 0, 0, 0, 0, shanghai)
+	runs := runWithFakeClock(t, start, start.AddDate(0, 0, 14), "0 0 2 * * *",
+		WithTimeZone("Asia/Shanghai"), WithCalendar(calendar))
+	assert.Equal(t, []string{
+		"2024-09-30T02:00:00+08:00",
+		"2024-10-08T02:00:00+08:00",
+		"2024-10-09T02:00:00+08:00",
+		"2024-10-10T02:00:00+08:00",
+		"2024-10-11T02:00:00+08:00",
+		"2024-10-12T02:00:00+08:00",
+	}, formatTimes(runs, shanghai))
+}
+
+func TestScheduleDST(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 02:30 does not exist on 2024-03-10, when the clocks are turned forward from 02:00 to 03:00.
+	start := time.Date(2024, 3, 9, 0, 0, 0, 0, newYork)
+	runs := runWithFakeClock(t, start, start.AddDate(0, 0, 3), "0 30 2 * * *", WithTimeZone("America/New_York"))
+	assert.Equal(t, []string{
+		"2024-03-09T02:30:00-05:00",
+		"2024-03-11T02:30:00-04:00",
+	}, formatTimes(runs, newYork))
+
+	// 01:30 happens twice on 2024-11-03, when the clocks are turned back from 02:00 to 01:00.
+	start = time.Date(2024, 11, 2, 0, 0, 0, 0, newYork)
+	runs = runWithFakeClock(t, start, start.AddDate(0, 0, 3), "0 30 1 * * *", WithTimeZone("America/New_York"))
+	assert.Equal(t, []string{
+		"2024-11-02T01:30:00-04:00",
+		"2024-11-03T01:30:00-04:00",
+		"2024-11-04T01:30:00-05:00",
+	}, formatTimes(runs, newYork))
+
+	// Rules at every hour keep running in the repeated hour.
+	r, err := parseRule("0 30 * * * *", newYork, time.Time{})
+	if assert.NoError(t, err) {
+		assert.False(t, r.dst)
+	}
+}
@@ -0,0 +1,39 @@
+package goschedule
+
+import "time"
+
+// ClockFailurePolicy decides what happens to a run when the SynchronizedClock
+// can not be set because the SynchronizationManager fails, e.g. on a redis outage.
+type ClockFailurePolicy int
+
+const (
+	// ClockFailureSkip skips the run, so no node runs the job while the storage is down.
+	ClockFailureSkip ClockFailurePolicy = iota
+	// ClockFailureRunAnyway runs the job, so every node may run the job while the storage is down.
+	ClockFailureRunAnyway
+	// ClockFailureRetry retries setting the clock with exponential backoff, and skips the run
+	// if all attempts fail.
+	ClockFailureRetry
+)
+
+const (
+	defaultClockRetryAttempts = 3
+	defaultClockRetryBackoff  = 100 * time.Millisecond
+)
+
+// WithClockFailurePolicy sets the ClockFailurePolicy of the Scheduler. The default is ClockFailureSkip.
+func WithClockFailurePolicy(policy ClockFailurePolicy) SchedulerOption {
+	return func(s *Scheduler) {
+		s.clockFailurePolicy = policy
+	}
+}
+
+// WithClockRetry sets the policy to ClockFailureRetry, with at most attempts tries in total.
+// The delay before the n-th retry is backoff * 2^(n-1).
+func WithClockRetry(attempts int, backoff time.Duration) SchedulerOption {
+	return func(s *Scheduler) {
+		s.clockFailurePolicy = ClockFailureRetry
+		s.clockRetryAttempts = attempts
+		s.clockRetryBackoff = backoff
+	}
+}
@@ -0,0 +1,104 @@
+package goschedule
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+	"github.com/stretchr/testify/assert"
+)
+
+var errStorageDown = errors.New("storage is down")
+
+// failingSynchronizationManager fails SetGreaterThanCtx a number of times before it delegates.
+type failingSynchronizationManager struct {
+	ContextSynchronizationManager
+	failures atomic.Int32
+}
+
+func (f *failingSynchronizationManager) SetGreaterThanCtx(ctx context.Context, key string, value int64) (bool, error) {
+	if f.failures.Add(-1) >= 0 {
+		return false, errStorageDown
+	}
+	return f.ContextSynchronizationManager.SetGreaterThanCtx(ctx, key, value)
+}
+
+func newFailingSynchronizationManager(failures int32) *failingSynchronizationManager {
+	f := &failingSynchronizationManager{
+		ContextSynchronizationManager: toContextSynchronizationManager(NewSynchronizationManagerMemory()),
+	}
+	f.failures.Store(failures)
+	return f
+}
+
+func TestClockFailurePolicy(t *testing.T) {
+	tests := []struct {
+		name          string
+		option        SchedulerOption
+		failures      int32
+		expectRun     bool
+		expectFailure uint64
+	}{
+		{
+			name:          "Skip",
+			option:        WithClockFailurePolicy(ClockFailureSkip),
+			failures:      1000,
+			expectRun:     false,
+			expectFailure: 1,
+		},
+		{
+			name:          "RunAnyway",
+			option:        WithClockFailurePolicy(ClockFailureRunAnyway),
+			failures:      1000,
+			expectRun:     true,
+			expectFailure: 1,
+		},
+		{
+			name:          "RetrySucceeds",
+			option:        WithClockRetry(3, 10*time.Millisecond),
+			failures:      2,
+			expectRun:     true,
+			expectFailure: 0,
+		},
+		{
+			name:          "RetryFails",
+			option:        WithClockRetry(3, 10*time.Millisecond),
+			failures:      1000,
+			expectRun:     false,
+			expectFailure: 1,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			clock := clockwork.NewFakeClockAt(testStart)
+			syncMgr := newFailingSynchronizationManager(test.failures)
+			s := newTestScheduler(t, WithClock(clock), WithContextSynchronizationManager(syncMgr), test.option)
+
+			var runs atomic.Int32
+			job, err := s.ScheduleSynchronizedJob("test-clock-failure-policy", "* * * * * *", func() {
+				runs.Add(1)
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer job.Stop()
+
+			// The retries back off by the clock as well.
+			clock.Advance(time.Second)
+			advanceUntil(t, clock, 10*time.Millisecond, func() bool {
+				return runs.Load() > 0 || job.ClockFailures() > 0
+			})
+			job.Stop()
+
+			assert.Equal(t, test.expectRun, runs.Load() > 0)
+			assert.GreaterOrEqual(t, job.ClockFailures(), test.expectFailure)
+			if test.expectFailure == 0 {
+				assert.Zero(t, job.ClockFailures())
+			}
+		})
+	}
+}
@@ -0,0 +1,185 @@
+package goschedule
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+// ErrConcurrencyLimit means a job is triggered on a node which is running as many jobs as its concurrency limit.
+var ErrConcurrencyLimit = errors.New("goschedule: the node has reached its concurrency limit")
+
+// OverlapPolicy decides what happens to a tick of a job while a previous run of the job
+// is still in progress, on any node of the cluster.
+type OverlapPolicy int
+
+const (
+	// OverlapAllow runs the tick anyway.
+	OverlapAllow OverlapPolicy = iota
+	// OverlapSkip skips the tick.
+	OverlapSkip
+	// OverlapQueue runs the tick once the previous run has finished.
+	OverlapQueue
+)
+
+const (
+	// minOverlapLeaseTTL and maxOverlapLeaseTTL bound the lease TTL derived from the interval of a job
+	// which does not allow overlapping runs.
+	minOverlapLeaseTTL = time.Second
+	maxOverlapLeaseTTL = time.Minute
+)
+
+// WithOverlapPolicy sets the OverlapPolicy of the job. The default is OverlapAllow.
+// A run is in progress as long as it holds the lease of the job, so the other policies
+// run the job in the lease mode. Unless WithLease is given as well, the lease TTL is twice the interval
+// between the ticks of the job, but within a second and a minute, so that the lease of a crashed node
+// only holds up a tick or two, and the tick of an expired lease is left with LeaseExpiredSkip.
+// OverlapQueue queues at most one tick of the job in the cluster, and skips the others.
+// The queued tick gives its slot of WithConcurrencyLimit back while waiting.
+func WithOverlapPolicy(policy OverlapPolicy) JobOption {
+	return func(options *jobOptions) {
+		options.overlapPolicy = policy
+	}
+}
+
+// overlapLeaseTTL returns the lease TTL of a job which does not allow overlapping runs, and has no WithLease.
+func overlapLeaseTTL(schedule cron.Schedule, now time.Time) time.Duration {
+	next := schedule.Next(now)
+	after := schedule.Next(next)
+	if next.IsZero() || after.IsZero() {
+		return maxOverlapLeaseTTL
+	}
+	return min(max(2*after.Sub(next), minOverlapLeaseTTL), maxOverlapLeaseTTL)
+}
+
+// WithConcurrencyLimit limits how many jobs run at the same time on this node.
+// A node running as many jobs as the limit leaves the ticks to the other nodes, and does not take over
+// expired leases. The shards assigned to it, and the missed ticks it catches up, wait for a slot instead.
+// A zero limit means no limit, which is the default.
+func WithConcurrencyLimit(limit int) SchedulerOption {
+	return func(s *Scheduler) {
+		s.slots = nil
+		if limit > 0 {
+			s.slots = make(chan struct{}, limit)
+		}
+	}
+}
+
+// tryAcquireSlot takes a slot to run a job on this node, unless all of them are taken.
+func (s *Scheduler) tryAcquireSlot() bool {
+	if s.slots == nil {
+		return true
+	}
+	select {
+	case s.slots <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+// acquireSlot waits for a slot to run a job on this node, until ctx is done.
+func (s *Scheduler) acquireSlot(ctx context.Context) bool {
+	if s.slots == nil {
+		return true
+	}
+	select {
+	case s.slots <- struct{}{}:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// releaseSlot returns a slot taken by tryAcquireSlot or acquireSlot.
+func (s *Scheduler) releaseSlot() {
+	if s.slots != nil {
+		<-s.slots
+	}
+}
+
+// tickSlot is the slot of this node taken by a tick, which the tick gives back while it waits
+// for other nodes. A tick may only take it again if it has given it back.
+type tickSlot struct {
+	scheduler *Scheduler
+	held      bool
+}
+
+// release gives the slot back, unless it has been given back already.
+func (t *tickSlot) release() {
+	if t.held {
+		t.held = false
+		t.scheduler.releaseSlot()
+	}
+}
+
+// reacquire waits for the slot again, until ctx is done.
+func (t *tickSlot) reacquire(ctx context.Context) bool {
+	if !t.held {
+		t.held = t.scheduler.acquireSlot(ctx)
+	}
+	return t.held
+}
+
+func (j *synchronizedJob) queueKey() string {
+	return "queue:" + j.id + ":" + j.rule
+}
+
+// enqueue claims the only queued tick of the job in the cluster, until the returned lease is released.
+func (j *synchronizedJob) enqueue(now time.Time) (Lease, bool) {
+	queued := Lease{
+		Owner:     j.scheduler.nodeID,
+		Tick:      now.UnixMilli(),
+		ExpiresAt: now.Add(j.options.leaseTTL).UnixMilli(),
+	}
+	_, acquired, err := j.leaseManager.AcquireLeaseCtx(j.ctx, j.queueKey(), queued, now.UnixMilli())
+	if err != nil {
+		logx.Errorf("Can not queue synchronized job %q: %v", j.id, err)
+		return queued, false
+	}
+	return queued, acquired
+}
+
+// queueLease waits until the lease of the job is acquired for the tick of lease, retrying every TTL/3,
+// and renewing the queued lease returned by enqueue meanwhile. The slot of the tick is only held
+// while trying. It returns the acquired lease with the slot held, or false if the job has been stopped.
+// The queued lease is released either way.
+func (j *synchronizedJob) queueLease(lease, queued Lease, slot *tickSlot) (Lease, bool) {
+	defer func() {
+		if err := j.leaseManager.ReleaseLeaseCtx(context.WithoutCancel(j.ctx), j.queueKey(), queued); err != nil {
+			logx.Errorf("Can not dequeue synchronized job %q: %v", j.id, err)
+		}
+	}()
+	ticker := j.scheduler.clock.NewTicker(j.options.leaseTTL / 3)
+	defer ticker.Stop()
+	slot.release()
+
+	for {
+		select {
+		case <-j.ctx.Done():
+			return lease, false
+		case <-ticker.Chan():
+		}
+		if !slot.reacquire(j.ctx) {
+			return lease, false
+		}
+
+		now := j.scheduler.clock.Now()
+		queued.ExpiresAt = now.Add(j.options.leaseTTL).UnixMilli()
+		if _, err := j.leaseManager.RenewLeaseCtx(j.ctx, j.queueKey(), queued); err != nil {
+			logx.Errorf("Can not renew the queued lease of synchronized job %q: %v", j.id, err)
+		}
+		lease.ExpiresAt = now.Add(j.options.leaseTTL).UnixMilli()
+		_, acquired, err := j.leaseManager.AcquireLeaseCtx(j.ctx, j.leaseKey, lease, now.UnixMilli())
+		if err != nil {
+			logx.Errorf("Can not acquire the lease of synchronized job %q: %v", j.id, err)
+		}
+		if acquired {
+			return lease, true
+		}
+		slot.release()
+	}
+}
@@ -0,0 +1,244 @@
+package goschedule
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+	"github.com/stretchr/testify/assert"
+)
+
+// activeRuns tracks the runs in progress, and the most of them at the same time.
+type activeRuns struct {
+	mu        sync.Mutex
+	active    int
+	max       int
+	scheduled []time.Time
+}
+
+// run runs until release is closed.
+func (a *activeRuns) run(ctx context.Context, release <-chan struct{}) {
+	a.mu.Lock()
+	a.active++
+	a.max = max(a.max, a.active)
+	a.scheduled = append(a.scheduled, ScheduledTime(ctx).UTC())
+	a.mu.Unlock()
+
+	<-release
+
+	a.mu.Lock()
+	a.active--
+	a.mu.Unlock()
+}
+
+func (a *activeRuns) activeCount() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.active
+}
+
+func TestOverlapSkip(t *testing.T) {
+	const id, rule = "test-overlap-skip", "* * * * * *"
+	clock := clockwork.NewFakeClockAt(testStart)
+	syncMgr := NewSynchronizationManagerMemory()
+
+	var runs activeRuns
+	var done, skipped atomic.Int32
+	release := make(chan struct{})
+	for _, nodeID := range []string{"node-a", "node-b"} {
+		hooks := countTicks(&done)
+		hooks.OnSkipped = func(string) {
+			skipped.Add(1)
+			done.Add(1)
+		}
+		s := newTestScheduler(t, WithClock(clock), WithSynchronizationManager(syncMgr), WithNodeID(nodeID),
+			WithHooks(hooks))
+		// The run takes longer than the interval, and the lease TTL is derived from it.
+		_, err := s.ScheduleSynchronizedJobContext(id, rule, func(ctx context.Context) error {
+			runs.run(ctx, release)
+			return nil
+		}, WithOverlapPolicy(OverlapSkip))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	advance(t, clock, time.Second, &done, 1)
+	assert.Eventually(t, func() bool { return runs.activeCount() == 1 }, time.Second, time.Millisecond)
+	// Every tick is skipped by the node losing it, and by the winner as well while the run is in progress.
+	advance(t, clock, time.Second, &done, 2)
+	advance(t, clock, time.Second, &done, 2)
+	assert.Equal(t, int32(5), skipped.Load())
+
+	close(release)
+	assert.Eventually(t, func() bool { return done.Load() == 6 }, time.Second, time.Millisecond)
+	advance(t, clock, time.Second, &done, 2)
+
+	runs.mu.Lock()
+	defer runs.mu.Unlock()
+	assert.Equal(t, 1, runs.max)
+	assert.Equal(t, []time.Time{testStart.Add(time.Second), testStart.Add(4 * time.Second)}, runs.scheduled)
+}
+
+func TestOverlapQueue(t *testing.T) {
+	const id, rule = "test-overlap-queue", "* * * * * *"
+	clock := clockwork.NewFakeClockAt(testStart)
+	syncMgr := NewSynchronizationManagerMemory()
+
+	var runs activeRuns
+	var done atomic.Int32
+	release := make(chan struct{})
+	for _, nodeID := range []string{"node-a", "node-b"} {
+		s := newTestScheduler(t, WithClock(clock), WithSynchronizationManager(syncMgr), WithNodeID(nodeID),
+			WithHooks(countTicks(&done)))
+		_, err := s.ScheduleSynchronizedJobContext(id, rule, func(ctx context.Context) error {
+			runs.run(ctx, release)
+			return nil
+		}, WithOverlapPolicy(OverlapQueue))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	advance(t, clock, time.Second, &done, 1)
+	assert.Eventually(t, func() bool { return runs.activeCount() == 1 }, time.Second, time.Millisecond)
+	// The next tick is queued by its winner, and the tick after it is skipped by both nodes.
+	advance(t, clock, time.Second, &done, 1)
+	advance(t, clock, time.Second, &done, 2)
+
+	// The queued tick runs once the lease is released, retrying every TTL/3, and only once.
+	close(release)
+	assert.Eventually(t, func() bool { return done.Load() == 5 }, time.Second, time.Millisecond)
+	waitLeaseReleased(t, syncMgr, "lease:"+id+":"+rule)
+	advance(t, clock, 700*time.Millisecond, &done, 1)
+
+	runs.mu.Lock()
+	defer runs.mu.Unlock()
+	assert.Equal(t, 1, runs.max)
+	assert.Equal(t, []time.Time{testStart.Add(time.Second), testStart.Add(2 * time.Second)}, runs.scheduled)
+}
+
+func TestConcurrencyLimit(t *testing.T) {
+	clock := clockwork.NewFakeClockAt(testStart)
+	var runs activeRuns
+	var done, skipped atomic.Int32
+	hooks := countTicks(&done)
+	hooks.OnSkipped = func(string) {
+		skipped.Add(1)
+		done.Add(1)
+	}
+	s := newTestScheduler(t, WithClock(clock), WithConcurrencyLimit(1), WithHooks(hooks))
+	release := make(chan struct{})
+	for _, id := range []string{"test-concurrency-a", "test-concurrency-b"} {
+		_, err := s.ScheduleSynchronizedJobContext(id, "* * * * * *", func(ctx context.Context) error {
+			runs.run(ctx, release)
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// One of the jobs takes the only slot, and the other one is skipped.
+	advance(t, clock, time.Second, &done, 1)
+	assert.Eventually(t, func() bool { return runs.activeCount() == 1 }, time.Second, time.Millisecond)
+	assert.ErrorIs(t, s.Trigger(context.Background(), "test-concurrency-a"), ErrConcurrencyLimit)
+	advance(t, clock, time.Second, &done, 2)
+	assert.Equal(t, int32(3), skipped.Load())
+
+	close(release)
+	assert.Eventually(t, func() bool { return done.Load() == 4 }, time.Second, time.Millisecond)
+	runs.mu.Lock()
+	defer runs.mu.Unlock()
+	assert.Equal(t, 1, runs.max)
+}
+
+func TestOverlapSharded(t *testing.T) {
+	s := newTestScheduler(t)
+	_, err := s.ScheduleShardedJob("test-overlap-sharded", "* * * * * *", 2,
+		func(context.Context, int, int) error { return nil }, WithOverlapPolicy(OverlapSkip))
+	assert.ErrorIs(t, err, ErrShardedLease)
+}
+
+func TestOverlapQueueOne(t *testing.T) {
+	const id = "test-overlap-queue-one"
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := clockwork.NewFakeClockAt(start)
+	syncMgr := NewSynchronizationManagerMemory()
+	var skipped atomic.Int32
+	s := newTestScheduler(t, WithClock(clock), WithSynchronizationManager(syncMgr), WithConcurrencyLimit(2), WithHooks(Hooks{
+		OnSkipped: func(string) { skipped.Add(1) },
+	}))
+
+	release := make(chan struct{})
+	ticks := make(chan time.Time, 10)
+	_, err := s.ScheduleSynchronizedJobContext(id, "* * * * * *", func(ctx context.Context) error {
+		ticks <- ScheduledTime(ctx)
+		<-release
+		return nil
+	}, WithOverlapPolicy(OverlapQueue))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = s.ScheduleSynchronizedJob("test-overlap-queue-other", yearlyRule, func() {})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// nextTick returns the scheduled time of the next run, failing the test if it does not run.
+	nextTick := func() time.Time {
+		select {
+		case tick := <-ticks:
+			return tick.UTC()
+		case <-time.After(time.Second):
+			t.Fatal("the job does not run")
+			return time.Time{}
+		}
+	}
+
+	clock.Advance(time.Second)
+	assert.Equal(t, start.Add(time.Second), nextTick())
+
+	// The next tick is queued, giving its slot back.
+	clock.Advance(time.Second)
+	assert.Eventually(t, func() bool { return len(s.slots) == 1 }, time.Second, time.Millisecond)
+	assert.NoError(t, s.Trigger(context.Background(), "test-overlap-queue-other"))
+
+	// The tick after it is skipped, as one tick is queued already.
+	clock.Advance(time.Second)
+	assert.Eventually(t, func() bool { return skipped.Load() == 1 }, time.Second, time.Millisecond)
+
+	// The queued tick runs once the lease is released, retrying every TTL/3.
+	close(release)
+	waitLeaseReleased(t, syncMgr, "lease:"+id+":* * * * * *")
+	clock.Advance(700 * time.Millisecond)
+	assert.Equal(t, start.Add(2*time.Second), nextTick())
+}
+
+// waitLeaseReleased waits until the lease under key has been released.
+func waitLeaseReleased(t *testing.T, syncMgr SynchronizationManager, key string) {
+	t.Helper()
+	assert.Eventually(t, func() bool {
+		_, ok, err := syncMgr.(LeaseManager).GetLeaseCtx(context.Background(), key)
+		return err == nil && !ok
+	}, time.Second, time.Millisecond)
+}
+
+func TestOverlapLeaseTTL(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for rule, ttl := range map[string]time.Duration{
+		"* * * * * *":              2 * time.Second,
+		"*/10 * * * * *":           20 * time.Second,
+		"0 0 * * * *":              time.Minute,
+		"@interval 100ms":          time.Second,
+		"@at 2024-01-02T00:00:00Z": time.Minute,
+	} {
+		r, err := parseRule(rule, time.UTC, time.Time{})
+		if assert.NoError(t, err) {
+			assert.Equal(t, ttl, overlapLeaseTTL(r.schedule, now), rule)
+		}
+	}
+}
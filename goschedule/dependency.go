@@ -0,0 +1,173 @@
+package goschedule
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+var (
+	// ErrDependencyTimeout is the error of a tick whose upstream jobs have not completed in time.
+	ErrDependencyTimeout = errors.New("goschedule: the upstream jobs have not completed in time")
+	// ErrDependencyFailed is the error of a tick whose upstream job has failed.
+	ErrDependencyFailed = errors.New("goschedule: an upstream job has failed")
+	// ErrDependencySkipped is the error of a tick whose upstream job has skipped it.
+	ErrDependencySkipped = errors.New("goschedule: an upstream job has skipped the tick")
+	// ErrShardedDependencies means a sharded job is scheduled with dependencies, which is not supported.
+	ErrShardedDependencies = errors.New("goschedule: sharded jobs do not support dependencies")
+)
+
+const (
+	// defaultDependencyTimeout is how long a job waits for its upstream jobs by default.
+	defaultDependencyTimeout = time.Hour
+	// dependencyPollInterval is how often a job checks whether its upstream jobs have completed.
+	dependencyPollInterval = time.Second
+)
+
+// WithDependencies makes the job run a tick only once the jobs of upstreamIDs have completed the tick
+// of the same scheduled time, on any node. The node winning the tick waits for them, and fails the tick
+// with ErrDependencyFailed as soon as one of them fails, with ErrDependencySkipped as soon as one of them
+// skips the tick, or with ErrDependencyTimeout after timeout. A failed tick counts as a failure of the job,
+// so the jobs depending on it fail in turn. The rule of the job is expected to be the same as the rules
+// of the upstream jobs.
+//
+// Every job records the scheduled time of its latest completed and failed runs, and of the latest tick
+// it skips because it is paused, excluded by its calendar, misfired or skipped by its OverlapPolicy,
+// so it can be upstream without any option. A node at its concurrency limit records the skip of a tick
+// as well, unless another node has won the tick by then. Only the latest of each is kept, so the jobs
+// are expected to tick less often than every few seconds, which is how often the waiting job checks them.
+// A triggered run does not wait for the upstream jobs, and is not recorded, as it is not a tick.
+// While waiting, the job gives back its slot of WithConcurrencyLimit, and waits for a slot again to run.
+// A zero timeout means an hour.
+// Sharded jobs do not support dependencies.
+func WithDependencies(timeout time.Duration, upstreamIDs ...string) JobOption {
+	return func(options *jobOptions) {
+		options.dependencyTimeout = timeout
+		options.upstreamIDs = upstreamIDs
+	}
+}
+
+func completedKey(jobID string) string {
+	return "completed:" + jobID
+}
+
+func failedKey(jobID string) string {
+	return "failed:" + jobID
+}
+
+func skippedKey(jobID string) string {
+	return "skipped:" + jobID
+}
+
+// recordCompletion records the result of the run of a tick for the jobs depending on this one.
+// The record expires like the clock of the job.
+func (j *synchronizedJob) recordCompletion(scheduled time.Time, err error) {
+	key := completedKey(j.id)
+	if err != nil {
+		key = failedKey(j.id)
+	}
+	ctx := context.WithoutCancel(j.ctx)
+	ttl := j.clockTTL(scheduled)
+	if _, err := setGreaterThan(ctx, j.scheduler.synchronizationManager, key, scheduled.UnixMilli(), ttl); err != nil {
+		logx.Errorf("Can not record the completion of synchronized job %q: %v", j.id, err)
+	}
+}
+
+// recordSkip records a tick which the job skips, so that the jobs depending on it do not wait for it.
+func (j *synchronizedJob) recordSkip(scheduled time.Time) {
+	ctx := context.WithoutCancel(j.ctx)
+	ttl := j.clockTTL(scheduled)
+	key := skippedKey(j.id)
+	if _, err := setGreaterThan(ctx, j.scheduler.synchronizationManager, key, scheduled.UnixMilli(), ttl); err != nil {
+		logx.Errorf("Can not record the skip of synchronized job %q: %v", j.id, err)
+	}
+}
+
+// recordUnclaimedSkip records a tick which this node leaves to the other nodes, unless one of them has won it.
+func (j *synchronizedJob) recordUnclaimedSkip(scheduled time.Time) {
+	won, ok, err := j.clock.GetCtx(j.ctx)
+	if err != nil {
+		logx.Errorf("Can not get synchronized clock of job %q: %v", j.id, err)
+		return
+	}
+	if ok && !won.Before(j.parsed.clockAfter(scheduled)) {
+		return
+	}
+	j.recordSkip(scheduled)
+}
+
+// awaitDependencies waits until the upstream jobs have completed the tick, without the slot of the tick,
+// which it takes again afterwards. It returns the error of the tick if they fail, skip or time out,
+// or the error of the context of the job if it is stopped.
+func (j *synchronizedJob) awaitDependencies(scheduled time.Time, slot *tickSlot) error {
+	timeout := j.options.dependencyTimeout
+	if timeout <= 0 {
+		timeout = defaultDependencyTimeout
+	}
+	deadline := j.scheduler.clock.After(timeout)
+	ticker := j.scheduler.clock.NewTicker(dependencyPollInterval)
+	defer ticker.Stop()
+
+	pending := j.options.upstreamIDs
+	for {
+		var err error
+		if pending, err = j.pendingDependencies(pending, scheduled); err != nil {
+			return err
+		}
+		if len(pending) == 0 {
+			if !slot.reacquire(j.ctx) {
+				return j.ctx.Err()
+			}
+			return nil
+		}
+		slot.release()
+
+		select {
+		case <-j.ctx.Done():
+			return j.ctx.Err()
+		case <-deadline:
+			return fmt.Errorf("%w: %v", ErrDependencyTimeout, pending)
+		case <-ticker.Chan():
+		}
+	}
+}
+
+// pendingDependencies returns the upstream jobs which have not completed the tick yet,
+// or ErrDependencyFailed or ErrDependencySkipped if any of them has failed or skipped it.
+func (j *synchronizedJob) pendingDependencies(upstreamIDs []string, scheduled time.Time) ([]string, error) {
+	tick := scheduled.UnixMilli()
+	syncMgr := j.scheduler.synchronizationManager
+	var pending []string
+	for _, id := range upstreamIDs {
+		failed, ok, err := syncMgr.GetCtx(j.ctx, failedKey(id))
+		if err != nil {
+			logx.Errorf("Can not get the completion of upstream job %q of synchronized job %q: %v", id, j.id, err)
+			pending = append(pending, id)
+			continue
+		}
+		if ok && failed == tick {
+			return nil, fmt.Errorf("%w: %q", ErrDependencyFailed, id)
+		}
+		skipped, ok, err := syncMgr.GetCtx(j.ctx, skippedKey(id))
+		if err != nil {
+			logx.Errorf("Can not get the skip of upstream job %q of synchronized job %q: %v", id, j.id, err)
+			pending = append(pending, id)
+			continue
+		}
+		if ok && skipped == tick {
+			return nil, fmt.Errorf("%w: %q", ErrDependencySkipped, id)
+		}
+
+		completed, ok, err := syncMgr.GetCtx(j.ctx, completedKey(id))
+		if err != nil {
+			logx.Errorf("Can not get the completion of upstream job %q of synchronized job %q: %v", id, j.id, err)
+		}
+		if err != nil || !ok || completed != tick {
+			pending = append(pending, id)
+		}
+	}
+	return pending, nil
+}
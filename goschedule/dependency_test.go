@@ -0,0 +1,279 @@
+package goschedule
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDependencies(t *testing.T) {
+	// The jobs tick every 10 seconds, as the job checks its upstream jobs every second.
+	clock := clockwork.NewFakeClockAt(testStart.Add(9 * time.Second))
+	s := newTestScheduler(t, WithClock(clock))
+	var mu sync.Mutex
+	var chain []string
+	schedule := func(id string, opts ...JobOption) {
+		_, err := s.ScheduleSynchronizedJobContext(id, "*/10 * * * * *", func(ctx context.Context) error {
+			if ScheduledTime(ctx).Equal(testStart.Add(10 * time.Second)) {
+				mu.Lock()
+				chain = append(chain, id)
+				mu.Unlock()
+			}
+			return nil
+		}, opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	schedule("test-dependencies-c", WithDependencies(0, "test-dependencies-b"))
+	schedule("test-dependencies-b", WithDependencies(0, "test-dependencies-a"))
+	schedule("test-dependencies-a")
+
+	// Each job of the chain runs the first tick after its upstream job has completed it.
+	advanceUntil(t, clock, time.Second, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(chain) == 3
+	})
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []string{"test-dependencies-a", "test-dependencies-b", "test-dependencies-c"}, chain)
+}
+
+func TestDependencyFailed(t *testing.T) {
+	clock := clockwork.NewFakeClockAt(testStart)
+	failures := make(chan error, 100)
+	s := newTestScheduler(t, WithClock(clock), WithHooks(Hooks{
+		OnFailure: func(jobID string, err error) {
+			if jobID == "test-dependency-failed-c" {
+				failures <- err
+			}
+		},
+	}))
+	var ran atomic.Bool
+	_, err := s.ScheduleSynchronizedJobContext("test-dependency-failed-c", "* * * * * *", func(ctx context.Context) error {
+		ran.Store(true)
+		return nil
+	}, WithDependencies(0, "test-dependency-failed-b"))
+	assert.NoError(t, err)
+	_, err = s.ScheduleSynchronizedJobContext("test-dependency-failed-b", "* * * * * *", func(ctx context.Context) error {
+		return nil
+	}, WithDependencies(0, "test-dependency-failed-a"))
+	assert.NoError(t, err)
+	_, err = s.ScheduleSynchronizedJobContext("test-dependency-failed-a", "* * * * * *", func(ctx context.Context) error {
+		return errTransient
+	})
+	assert.NoError(t, err)
+
+	var failure error
+	advanceUntil(t, clock, time.Second, func() bool {
+		select {
+		case failure = <-failures:
+			return true
+		default:
+			return false
+		}
+	})
+	assert.ErrorIs(t, failure, ErrDependencyFailed)
+	assert.ErrorContains(t, failure, "test-dependency-failed-b")
+	assert.False(t, ran.Load())
+}
+
+func TestDependencyTimeout(t *testing.T) {
+	clock := clockwork.NewFakeClockAt(testStart)
+	recorder := NewRunRecorderMemory(0)
+	failures := make(chan error, 10)
+	s := newTestScheduler(t, WithClock(clock), WithRunRecorder(recorder), WithHooks(Hooks{
+		OnFailure: func(jobID string, err error) { failures <- err },
+	}))
+	_, err := s.ScheduleSynchronizedJob("test-dependency-timeout", "* * * * * *", func() {
+		t.Error("the job runs without its upstream job")
+	}, WithDependencies(100*time.Millisecond, "test-dependency-missing"))
+	assert.NoError(t, err)
+
+	var failure error
+	advanceUntil(t, clock, time.Second, func() bool {
+		select {
+		case failure = <-failures:
+			return true
+		default:
+			return false
+		}
+	})
+	assert.ErrorIs(t, failure, ErrDependencyTimeout)
+	records, err := s.LastRuns(context.Background(), "test-dependency-timeout", 1)
+	assert.NoError(t, err)
+	if assert.Len(t, records, 1) {
+		assert.Contains(t, records[0].Error, "test-dependency-missing")
+	}
+}
+
+func TestDependencySharded(t *testing.T) {
+	s := newTestScheduler(t)
+	_, err := s.ScheduleShardedJob("test-dependency-sharded", "* * * * * *", 2,
+		func(context.Context, int, int) error { return nil }, WithDependencies(0, "test-dependency-upstream"))
+	assert.ErrorIs(t, err, ErrShardedDependencies)
+}
+
+func TestDependencySkipped(t *testing.T) {
+	clock := clockwork.NewFakeClockAt(testStart)
+	failures := make(chan error, 10)
+	s := newTestScheduler(t, WithClock(clock), WithHooks(Hooks{
+		OnFailure: func(jobID string, err error) { failures <- err },
+	}))
+	_, err := s.ScheduleSynchronizedJob("test-dependency-skipped-b", "* * * * * *", func() {
+		t.Error("the job runs without its upstream job")
+	}, WithDependencies(0, "test-dependency-skipped-a"))
+	assert.NoError(t, err)
+	_, err = s.ScheduleSynchronizedJob("test-dependency-skipped-a", "* * * * * *", func() {})
+	assert.NoError(t, err)
+	assert.NoError(t, s.Pause(context.Background(), "test-dependency-skipped-a"))
+
+	// The job fails as soon as its paused upstream job skips the tick, without waiting for the timeout.
+	var failure error
+	advanceUntil(t, clock, time.Second, func() bool {
+		select {
+		case failure = <-failures:
+			return true
+		default:
+			return false
+		}
+	})
+	assert.ErrorIs(t, failure, ErrDependencySkipped)
+	assert.ErrorContains(t, failure, "test-dependency-skipped-a")
+}
+
+func TestDependencySlot(t *testing.T) {
+	const rule = "@at 2024-01-01T00:00:01Z"
+	clock := clockwork.NewFakeClockAt(testStart)
+	syncMgr := NewSynchronizationManagerMemory()
+	s := newTestScheduler(t, WithClock(clock), WithSynchronizationManager(syncMgr), WithConcurrencyLimit(1))
+	ticks := make(chan time.Time, 1)
+	_, err := s.ScheduleSynchronizedJobContext("test-dependency-slot-b", rule, func(ctx context.Context) error {
+		ticks <- ScheduledTime(ctx)
+		return nil
+	}, WithDependencies(0, "test-dependency-slot-a"))
+	assert.NoError(t, err)
+	_, err = s.ScheduleSynchronizedJob("test-dependency-slot-c", yearlyRule, func() {})
+	assert.NoError(t, err)
+	// The upstream job runs on another node.
+	release := make(chan struct{})
+	_, err = newTestScheduler(t, WithClock(clock), WithSynchronizationManager(syncMgr)).
+		ScheduleSynchronizedJob("test-dependency-slot-a", rule, func() { <-release })
+	assert.NoError(t, err)
+
+	// The waiting job gives its slot back, so that other jobs can run meanwhile.
+	clock.Advance(time.Second)
+	assert.Eventually(t, func() bool { return len(s.slots) == 0 }, time.Second, time.Millisecond)
+	assert.NoError(t, s.Trigger(context.Background(), "test-dependency-slot-c"))
+
+	// The job runs at the next poll after the completion of its upstream job.
+	close(release)
+	var tick time.Time
+	advanceUntil(t, clock, time.Second, func() bool {
+		select {
+		case tick = <-ticks:
+			return true
+		default:
+			return false
+		}
+	})
+	assert.Equal(t, testStart.Add(time.Second), tick.UTC())
+}
+
+func TestDependencyTriggered(t *testing.T) {
+	clock := clockwork.NewFakeClockAt(testStart)
+	failures := make(chan error, 1)
+	s := newTestScheduler(t, WithClock(clock), WithHooks(Hooks{
+		OnFailure: func(jobID string, err error) { failures <- err },
+	}))
+	_, err := s.ScheduleSynchronizedJob("test-dependency-triggered-b", "@at 2024-01-01T00:00:01Z", func() {
+		t.Error("the job should not run")
+	}, WithDependencies(3*time.Second, "test-dependency-triggered-a"))
+	assert.NoError(t, err)
+	_, err = s.ScheduleSynchronizedJob("test-dependency-triggered-a", yearlyRule, func() {})
+	assert.NoError(t, err)
+
+	// A triggered run of the upstream job does not complete the tick.
+	clock.Advance(time.Second)
+	assert.NoError(t, s.Trigger(context.Background(), "test-dependency-triggered-a"))
+	var failure error
+	advanceUntil(t, clock, time.Second, func() bool {
+		select {
+		case failure = <-failures:
+			return true
+		default:
+			return false
+		}
+	})
+	assert.ErrorIs(t, failure, ErrDependencyTimeout)
+}
+
+func TestDependencySkippedByLimit(t *testing.T) {
+	const rule = "@at 2024-01-01T00:00:01Z"
+	clock := clockwork.NewFakeClockAt(testStart)
+	syncMgr := NewSynchronizationManagerMemory()
+	failures := make(chan error, 10)
+	downstream := newTestScheduler(t, WithClock(clock), WithSynchronizationManager(syncMgr), WithHooks(Hooks{
+		OnFailure: func(jobID string, err error) { failures <- err },
+	}))
+	_, err := downstream.ScheduleSynchronizedJob("test-dependency-limit-b", rule, func() {
+		t.Error("the job runs without its upstream job")
+	}, WithDependencies(0, "test-dependency-limit-a"))
+	assert.NoError(t, err)
+
+	// The only node of the upstream job is busy at the tick, so nobody runs it.
+	release := make(chan struct{})
+	defer close(release)
+	upstream := newTestScheduler(t, WithClock(clock), WithSynchronizationManager(syncMgr), WithConcurrencyLimit(1))
+	_, err = upstream.ScheduleSynchronizedJob("test-dependency-limit-busy", yearlyRule, func() { <-release })
+	assert.NoError(t, err)
+	_, err = upstream.ScheduleSynchronizedJob("test-dependency-limit-a", rule, func() {})
+	assert.NoError(t, err)
+	assert.NoError(t, upstream.Trigger(context.Background(), "test-dependency-limit-busy"))
+
+	var failure error
+	advanceUntil(t, clock, time.Second, func() bool {
+		select {
+		case failure = <-failures:
+			return true
+		default:
+			return false
+		}
+	})
+	assert.ErrorIs(t, failure, ErrDependencySkipped)
+}
+
+func TestDependencySkippedByOverlap(t *testing.T) {
+	clock := clockwork.NewFakeClockAt(testStart)
+	failures := make(chan error, 10)
+	s := newTestScheduler(t, WithClock(clock), WithHooks(Hooks{
+		OnFailure: func(jobID string, err error) { failures <- err },
+	}))
+	_, err := s.ScheduleSynchronizedJob("test-dependency-overlap-b", "*/5 * * * * *", func() {},
+		WithDependencies(0, "test-dependency-overlap-a"))
+	assert.NoError(t, err)
+	// The first run of the upstream job takes longer than its interval, so it skips the next tick.
+	release := make(chan struct{})
+	defer close(release)
+	_, err = s.ScheduleSynchronizedJob("test-dependency-overlap-a", "*/5 * * * * *", func() { <-release },
+		WithOverlapPolicy(OverlapSkip))
+	assert.NoError(t, err)
+
+	clock.Advance(5 * time.Second)
+	var failure error
+	advanceUntil(t, clock, time.Second, func() bool {
+		select {
+		case failure = <-failures:
+			return true
+		default:
+			return false
+		}
+	})
+	assert.ErrorIs(t, failure, ErrDependencySkipped)
+}
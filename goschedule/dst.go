@@ -0,0 +1,49 @@
+package goschedule
+
+import (
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// everyHour is the bits of the hour field of a cron rule matching all hours.
+const everyHour = 1<<24 - 1
+
+// dstSchedule runs the ticks of a cron rule at fixed hours once on the day when the clocks
+// are turned back, e.g. at the end of DST, as Vixie cron does. Without it, a job at 01:30
+// would run twice on such a day in America/New_York. The ticks in the hour skipped when
+// the clocks are turned forward do not happen, as in robfig/cron.
+type dstSchedule struct {
+	spec *cron.SpecSchedule
+}
+
+// withDST wraps schedule in a dstSchedule if it is a cron rule at fixed hours.
+func withDST(schedule cron.Schedule) cron.Schedule {
+	spec, ok := schedule.(*cron.SpecSchedule)
+	if !ok || spec.Hour&everyHour == everyHour {
+		return schedule
+	}
+	return dstSchedule{spec}
+}
+
+func (s dstSchedule) Next(t time.Time) time.Time {
+	t = s.spec.Next(t)
+	for !t.IsZero() && repeatsWallClock(t, s.spec.Location) {
+		t = s.spec.Next(t)
+	}
+	return t
+}
+
+// repeatsWallClock reports whether the wall clock time of t in location has happened before,
+// because the clocks have been turned back within a day before t.
+func repeatsWallClock(t time.Time, location *time.Location) bool {
+	t = t.In(location)
+	_, offset := t.Zone()
+	_, dayBefore := t.Add(-24 * time.Hour).Zone()
+	if dayBefore <= offset {
+		return false
+	}
+	earlier := t.Add(-time.Duration(dayBefore-offset) * time.Second)
+	_, earlierOffset := earlier.Zone()
+	return earlierOffset == dayBefore && earlier.Format(time.DateTime) == t.Format(time.DateTime)
+}
@@ -0,0 +1,70 @@
+package goschedule
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ExpiringSynchronizationManager is implemented by the SynchronizationManagers whose keys can expire,
+// so that the clocks of the removed jobs do not stay forever.
+type ExpiringSynchronizationManager interface {
+	// SetGreaterThanTTLCtx is like SetGreaterThanCtx, but the key expires ttl after it is set.
+	SetGreaterThanTTLCtx(ctx context.Context, key string, value int64, ttl time.Duration) (bool, error)
+}
+
+// Pruner is implemented by the SynchronizationManagers which can delete the clocks of the removed jobs.
+type Pruner interface {
+	// Prune deletes the clocks which are not of activeJobs, including their shard clocks, and returns
+	// how many clocks are deleted. activeJobs must list the Jobs of all nodes sharing the storage.
+	Prune(ctx context.Context, activeJobs []JobInfo) (int, error)
+}
+
+const (
+	// clockTTLIntervals is how many intervals of its job a clock is kept after its latest tick.
+	clockTTLIntervals = 10
+	// minClockTTL is how long a clock is kept at least.
+	minClockTTL = 24 * time.Hour
+	// triggerTTL is how long the key deduplicating the triggers of a job is kept.
+	triggerTTL = time.Minute
+)
+
+// setGreaterThan is SetGreaterThanCtx, but key expires after ttl if syncMgr is an ExpiringSynchronizationManager.
+func setGreaterThan(ctx context.Context, syncMgr ContextSynchronizationManager, key string, value int64,
+	ttl time.Duration) (bool, error) {
+	if expiring, ok := syncMgr.(ExpiringSynchronizationManager); ok && ttl > 0 {
+		return expiring.SetGreaterThanTTLCtx(ctx, key, value, ttl)
+	}
+	return syncMgr.SetGreaterThanCtx(ctx, key, value)
+}
+
+// clockTTL returns how long to keep the clock set to timestamp, the tick after the latest one:
+// ten intervals of the job after timestamp, but at least a day.
+func (j *synchronizedJob) clockTTL(timestamp time.Time) time.Duration {
+	ttl := timestamp.Sub(j.scheduler.clock.Now())
+	if next := j.schedule.Next(timestamp); !next.IsZero() {
+		ttl += clockTTLIntervals * next.Sub(timestamp)
+	}
+	return max(ttl, minClockTTL)
+}
+
+// isStaleClock reports whether key is a clock, but not the clock of one of activeJobs or of one of their shards.
+// The clocks are keyed by clock:<job ID>:<rule>[:shard:<index>], which is matched exactly, as the IDs may have colons.
+func isStaleClock(key string, activeJobs []JobInfo) bool {
+	if !strings.HasPrefix(key, "clock:") {
+		return false
+	}
+	for _, job := range activeJobs {
+		clock := "clock:" + job.ID + ":" + job.Rule
+		if key == clock {
+			return false
+		}
+		if index, ok := strings.CutPrefix(key, clock+":shard:"); ok {
+			if _, err := strconv.ParseUint(index, 10, 0); err == nil {
+				return false
+			}
+		}
+	}
+	return true
+}
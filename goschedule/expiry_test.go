@@ -0,0 +1,142 @@
+package goschedule
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+	"github.com/stretchr/testify/assert"
+	"github.com/zeromicro/go-zero/core/stores/redis"
+)
+
+func TestIsStaleClock(t *testing.T) {
+	active := []JobInfo{{ID: "report", Rule: "0 0 2 * * *"}, {ID: "cleanup", Rule: "@every 1h"}, {ID: "a", Rule: "@daily"}}
+	assert.False(t, isStaleClock("clock:report:0 0 2 * * *", active))
+	assert.False(t, isStaleClock("clock:cleanup:@every 1h:shard:3", active))
+	assert.True(t, isStaleClock("clock:cleanup:@every 1h:shard:x", active))
+	assert.True(t, isStaleClock("clock:reports:0 0 2 * * *", active))
+	assert.True(t, isStaleClock("clock:report:0 0 3 * * *", active))
+	assert.True(t, isStaleClock("clock:removed:* * * * * *", active))
+	// The clock of a removed job whose ID starts with the ID of an active job and a colon.
+	assert.True(t, isStaleClock("clock:a:b:@daily", active))
+	assert.False(t, isStaleClock("paused:removed", active))
+}
+
+func TestClockTTL(t *testing.T) {
+	now := time.Date(2024, 10, 1, 0, 0, 0, 0, time.UTC)
+	s := newTestScheduler(t, WithClock(clockwork.NewFakeClockAt(now)))
+	job, err := s.ScheduleSynchronizedJob("test-clock-ttl", "@every 3h", func() {})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 33*time.Hour, job.job.clockTTL(now.Add(3*time.Hour)))
+
+	job, err = s.ScheduleSynchronizedJob("test-clock-ttl-min", "* * * * * *", func() {})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, minClockTTL, job.job.clockTTL(now.Add(time.Second)))
+}
+
+func TestPruneMemory(t *testing.T) {
+	syncMgr := NewSynchronizationManagerMemory()
+	ctx := context.Background()
+	syncMgr.Set("clock:active:* * * * * *", 1)
+	_, err := syncMgr.(ExpiringSynchronizationManager).SetGreaterThanTTLCtx(ctx, "clock:removed:* * * * * *", 1, time.Hour)
+	assert.NoError(t, err)
+	syncMgr.Set("paused:removed", 1)
+
+	pruned, err := syncMgr.(Pruner).Prune(ctx, []JobInfo{{ID: "active", Rule: "* * * * * *"}})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, pruned)
+	assert.True(t, syncMgr.Exists("clock:active:* * * * * *"))
+	assert.False(t, syncMgr.Exists("clock:removed:* * * * * *"))
+	assert.True(t, syncMgr.Exists("paused:removed"))
+}
+
+func TestPruneRedis(t *testing.T) {
+	const namespace = "TestPruneRedis*"
+	store := redis.MustNewRedis(redis.RedisConf{Host: "127.0.0.1:6379", Type: "node"})
+	keys, _ := store.Keys(escapeGlob(namespace) + ":*")
+	if len(keys) > 0 {
+		_, _ = store.Del(keys...)
+	}
+	syncMgr := NewSynchronizationManagerRedis(store, namespace)
+	expiring := syncMgr.(ExpiringSynchronizationManager)
+	ctx := context.Background()
+
+	wasSet, err := expiring.SetGreaterThanTTLCtx(ctx, "clock:active:* * * * * *", 2, time.Hour)
+	assert.NoError(t, err)
+	assert.True(t, wasSet)
+	wasSet, err = expiring.SetGreaterThanTTLCtx(ctx, "clock:active:* * * * * *", 1, time.Hour)
+	assert.NoError(t, err)
+	assert.False(t, wasSet)
+	ttl, err := store.Ttl(namespace + ":clock:active:* * * * * *")
+	assert.NoError(t, err)
+	assert.InDelta(t, time.Hour.Seconds(), ttl, 1)
+
+	_, err = expiring.SetGreaterThanTTLCtx(ctx, "clock:removed:* * * * * *:shard:0", 1, time.Hour)
+	assert.NoError(t, err)
+	syncMgr.Set("clock:legacy:* * * * * *", 1)
+	syncMgr.Set("paused:removed", 1)
+	// A key of another namespace matching the pattern of the namespace.
+	_ = store.Set("TestPruneRedisX:clock:removed:* * * * * *", "1")
+	defer func() { _, _ = store.Del("TestPruneRedisX:clock:removed:* * * * * *") }()
+
+	pruned, err := syncMgr.(Pruner).Prune(ctx, []JobInfo{{ID: "active", Rule: "* * * * * *"}})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, pruned)
+	assert.True(t, syncMgr.Exists("clock:active:* * * * * *"))
+	assert.False(t, syncMgr.Exists("clock:removed:* * * * * *:shard:0"))
+	assert.False(t, syncMgr.Exists("clock:legacy:* * * * * *"))
+	assert.True(t, syncMgr.Exists("paused:removed"))
+	exists, _ := store.Exists("TestPruneRedisX:clock:removed:* * * * * *")
+	assert.True(t, exists)
+}
+
+func TestPruneRedisCluster(t *testing.T) {
+	const namespace = "TestPruneRedisCluster"
+	store := redis.New("127.0.0.1:6379", redis.Cluster())
+	syncMgr := NewSynchronizationManagerRedis(store, namespace)
+	assert.Equal(t, namespace+":clock:x", syncMgr.(*synchronizationManagerRedis).getNamespacedKey("clock:x"))
+	syncMgr.Set("clock:active:* * * * * *", 1)
+	syncMgr.Set("clock:removed:* * * * * *", 1)
+
+	pruned, err := syncMgr.(Pruner).Prune(context.Background(), []JobInfo{{ID: "active", Rule: "* * * * * *"}})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, pruned)
+	assert.True(t, syncMgr.Exists("clock:active:* * * * * *"))
+	assert.False(t, syncMgr.Exists("clock:removed:* * * * * *"))
+	_, _ = store.Del(namespace + ":clock:active:* * * * * *")
+}
+
+func TestClusterOptions(t *testing.T) {
+	options := clusterOptions(redis.New("10.0.0.1:6379,10.0.0.2:6379", redis.Cluster(), redis.WithPass("secret")))
+	assert.Equal(t, []string{"10.0.0.1:6379", "10.0.0.2:6379"}, options.Addrs)
+	assert.Equal(t, "secret", options.Password)
+	assert.Nil(t, options.TLSConfig)
+
+	options = clusterOptions(redis.New("10.0.0.1:6379", redis.Cluster(), redis.WithTLS()))
+	assert.NotNil(t, options.TLSConfig)
+}
+
+func TestScheduleClockTTL(t *testing.T) {
+	const id, rule = "test-schedule-clock-ttl", "* * * * * *"
+	clock := clockwork.NewFakeClockAt(testStart)
+	syncMgr := NewSynchronizationManagerMemory()
+	memory := syncMgr.(*synchronizationManagerMemory)
+	memory.now = clock.Now
+	var done atomic.Int32
+	s := newTestScheduler(t, WithClock(clock), WithSynchronizationManager(syncMgr), WithHooks(countTicks(&done)))
+	_, err := s.ScheduleSynchronizedJob(id, rule, func() {})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	advance(t, clock, time.Second, &done, 1)
+	memory.mu.Lock()
+	defer memory.mu.Unlock()
+	assert.Equal(t, clock.Now().Add(minClockTTL), memory.store["clock:"+id+":"+rule].expiresAt)
+}
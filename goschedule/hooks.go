@@ -0,0 +1,65 @@
+package goschedule
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aclisp/go-zero-side/mailx"
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+// Hooks are called on every tick of the jobs of a Scheduler. Any of them can be nil.
+// They are called on the goroutine of the job, so a slow hook delays the job.
+type Hooks struct {
+	// OnSuccess is called after the job has run without error on this node.
+	OnSuccess func(jobID string)
+	// OnFailure is called after the job has returned an error or panicked on this node.
+	// The error of a panic is a *PanicError.
+	OnFailure func(jobID string, err error)
+	// OnSkipped is called when the job did not run on this node, because another node has won the tick, the job is paused,
+	// the previous run is still in progress or the node has reached its concurrency limit.
+	OnSkipped func(jobID string)
+}
+
+// WithHooks sets the Hooks of the Scheduler.
+func WithHooks(hooks Hooks) SchedulerOption {
+	return func(s *Scheduler) {
+		s.hooks = hooks
+	}
+}
+
+// PanicError is the error of a job which has panicked.
+type PanicError struct {
+	// Value is the value recovered from the panic.
+	Value any
+	// Stack is the stack trace of the goroutine where the panic happened.
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.Value)
+}
+
+// MailOnFailure returns an OnFailure hook which sends an email about the failure of a job.
+// Usually send is the DialAndSend method of a *mailx.Dialer.
+func MailOnFailure(send func(m ...*mailx.Message) error, from string, to ...string) func(jobID string, err error) {
+	return func(jobID string, err error) {
+		m := mailx.NewMessage()
+		m.SetHeader("From", from)
+		m.SetHeader("To", to...)
+		m.SetHeader("Subject", fmt.Sprintf("Synchronized job %q failed", jobID))
+		m.SetDateHeader("Date", time.Now())
+
+		body := err.Error()
+		var panicErr *PanicError
+		if errors.As(err, &panicErr) {
+			body += "\n\n" + string(panicErr.Stack)
+		}
+		m.SetBody("text/plain", body)
+
+		if err := send(m); err != nil {
+			logx.Errorf("Can not mail the failure of synchronized job %q: %v", jobID, err)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package goschedule
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aclisp/go-zero-side/mailx"
+	"github.com/jonboulle/clockwork"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHooks(t *testing.T) {
+	syncMgr := NewSynchronizationManagerXorm(newTestEngine(t), "synchronization")
+	recorder := NewRunRecorderMemory(0)
+
+	type failure struct {
+		jobID string
+		err   error
+	}
+	failures := make(chan failure, 10)
+	successes := make(chan string, 10)
+	skips := make(chan string, 10)
+	hooks := Hooks{
+		OnSuccess: func(jobID string) { successes <- jobID },
+		OnFailure: func(jobID string, err error) { failures <- failure{jobID, err} },
+		OnSkipped: func(jobID string) { skips <- jobID },
+	}
+
+	clock := clockwork.NewFakeClockAt(testStart)
+	for _, nodeID := range []string{"node-a", "node-b"} {
+		s := newTestScheduler(t, WithClock(clock), WithSynchronizationManager(syncMgr), WithRunRecorder(recorder),
+			WithNodeID(nodeID), WithHooks(hooks))
+		_, err := s.ScheduleSynchronizedJob("test-hooks-panic", "* * * * * *", func() {
+			panic("boom")
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = s.ScheduleSynchronizedJob("test-hooks-success", "* * * * * *", func() {})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	clock.Advance(time.Second)
+
+	select {
+	case f := <-failures:
+		assert.Equal(t, "test-hooks-panic", f.jobID)
+		var panicErr *PanicError
+		if assert.ErrorAs(t, f.err, &panicErr) {
+			assert.Equal(t, "boom", panicErr.Value)
+			assert.Contains(t, string(panicErr.Stack), "TestHooks")
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("OnFailure is not called")
+	}
+
+	select {
+	case jobID := <-successes:
+		assert.Equal(t, "test-hooks-success", jobID)
+	case <-time.After(3 * time.Second):
+		t.Fatal("OnSuccess is not called")
+	}
+
+	select {
+	case <-skips:
+	case <-time.After(3 * time.Second):
+		t.Fatal("OnSkipped is not called")
+	}
+
+	assert.Eventually(t, func() bool {
+		records, _ := recorder.LastRuns(context.Background(), "test-hooks-panic", 10)
+		for _, record := range records {
+			if !record.Skipped {
+				return assert.Contains(t, record.Panic, "boom")
+			}
+		}
+		return false
+	}, 3*time.Second, 10*time.Millisecond)
+}
+
+func TestMailOnFailure(t *testing.T) {
+	var sent []*mailx.Message
+	onFailure := MailOnFailure(func(m ...*mailx.Message) error {
+		sent = append(sent, m...)
+		return nil
+	}, "from@example.com", "to@example.com")
+
+	onFailure("job", &PanicError{Value: "boom", Stack: []byte("goroutine 1 [running]")})
+	onFailure("job", errors.New("job failed"))
+
+	if assert.Len(t, sent, 2) {
+		assert.Equal(t, []string{"from@example.com"}, sent[0].GetHeader("From"))
+		assert.Equal(t, []string{"to@example.com"}, sent[0].GetHeader("To"))
+		assert.Equal(t, []string{`Synchronized job "job" failed`}, sent[0].GetHeader("Subject"))
+
+		var buf bytes.Buffer
+		_, err := sent[0].WriteTo(&buf)
+		assert.NoError(t, err)
+		assert.Contains(t, buf.String(), "panic: boom")
+		assert.Contains(t, buf.String(), "goroutine 1 [running]")
+
+		buf.Reset()
+		_, err = sent[1].WriteTo(&buf)
+		assert.NoError(t, err)
+		assert.Contains(t, buf.String(), "job failed")
+	}
+}
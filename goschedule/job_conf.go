@@ -0,0 +1,138 @@
+package goschedule
+
+//lint:file-ignore SA5008 Use gozero config tags
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+// JobConf is the configuration of a synchronized job, which is usually a list
+// in the go-zero config of a service, loaded by conf.MustLoad:
+//
+//	Jobs:
+//	  - ID: daily-report
+//	    Rule: 0 0 2 * * *
+//	    TimeZone: Asia/Shanghai
+//	    Timeout: 10m
+//	    Misfire: once
+//
+//nolint:staticcheck
+type JobConf struct {
+	// ID is the ID of the job.
+	ID string
+	// Handler is the name of the handler of the job. The default is the ID.
+	Handler string `json:",optional"`
+	// Rule is a cron expression with optional seconds, or a rule starting with RuleInterval, RuleAt or RuleStartup.
+	Rule string
+	// TimeZone is the IANA time zone of the job. The default is the local time zone of the server.
+	TimeZone string `json:",optional"`
+	// Timeout limits the duration of each run. The default is no limit.
+	Timeout time.Duration `json:",optional"`
+	// Enabled is false for the jobs which are validated but not scheduled. It defaults to true when loaded by conf.
+	Enabled bool `json:",default=true"`
+	// Misfire is the MisfirePolicy of the job.
+	Misfire string `json:",default=skip,options=skip|once|all"`
+	// MisfireMaxRuns is how many missed ticks the "all" MisfirePolicy runs at most.
+	MisfireMaxRuns int `json:",default=10,range=[1:]"`
+}
+
+var misfirePolicies = map[string]MisfirePolicy{
+	"":     MisfireSkip,
+	"skip": MisfireSkip,
+	"once": MisfireRunOnce,
+	"all":  MisfireRunAll,
+}
+
+// handler returns the name of the handler of the job.
+func (c JobConf) handler() string {
+	if c.Handler != "" {
+		return c.Handler
+	}
+	return c.ID
+}
+
+// options returns the JobOptions configured by c.
+func (c JobConf) options() []JobOption {
+	opts := []JobOption{WithMisfirePolicy(misfirePolicies[c.Misfire], c.MisfireMaxRuns)}
+	if c.TimeZone != "" {
+		opts = append(opts, WithTimeZone(c.TimeZone))
+	}
+	if c.Timeout > 0 {
+		opts = append(opts, WithTimeout(c.Timeout))
+	}
+	return opts
+}
+
+// validate checks c without scheduling it.
+func (c JobConf) validate(handlers map[string]func(ctx context.Context) error) error {
+	if c.ID == "" {
+		return fmt.Errorf("goschedule: job of rule %q has no ID", c.Rule)
+	}
+	if _, ok := handlers[c.handler()]; !ok {
+		return fmt.Errorf("goschedule: job %q has unknown handler %q", c.ID, c.handler())
+	}
+	if _, ok := misfirePolicies[c.Misfire]; !ok {
+		return fmt.Errorf("goschedule: job %q has invalid misfire policy %q", c.ID, c.Misfire)
+	}
+	location := time.Local
+	if c.TimeZone != "" {
+		var err error
+		if location, err = time.LoadLocation(c.TimeZone); err != nil {
+			return fmt.Errorf("goschedule: job %q: %w", c.ID, err)
+		}
+	}
+	if _, err := parseRule(c.Rule, location, time.Time{}); err != nil {
+		return fmt.Errorf("goschedule: job %q: %w", c.ID, err)
+	}
+	return nil
+}
+
+// ScheduleJobs schedules the enabled jobs of confs, binding each of them to the handler of its name.
+// All confs are validated first, so nothing is scheduled if any of them has an unknown handler,
+// an invalid rule or time zone, or the same ID as another one. opts apply to all jobs,
+// but the options configured by the confs take precedence.
+func (s *Scheduler) ScheduleJobs(confs []JobConf, handlers map[string]func(ctx context.Context) error,
+	opts ...JobOption) ([]ScheduledJob, error) {
+	ids := make(map[string]bool, len(confs))
+	for _, c := range confs {
+		if err := c.validate(handlers); err != nil {
+			return nil, err
+		}
+		if ids[c.ID] {
+			return nil, fmt.Errorf("goschedule: job %q is configured twice", c.ID)
+		}
+		ids[c.ID] = true
+	}
+
+	var jobs []ScheduledJob
+	for _, c := range confs {
+		if !c.Enabled {
+			logx.Infof("Synchronized job %q is disabled, skip scheduling it", c.ID)
+			continue
+		}
+		handler := handlers[c.handler()]
+		job, err := s.ScheduleSynchronizedJobContext(c.ID, c.Rule, handler, slices.Concat(opts, c.options())...)
+		if err != nil {
+			// Unlike Stop, the rollback keeps the clocks, which the other nodes may be using.
+			for _, job := range jobs {
+				job.job.remove()
+			}
+			return nil, fmt.Errorf("goschedule: job %q: %w", c.ID, err)
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, nil
+}
+
+// MustScheduleJobs is like ScheduleJobs but exits on error.
+func (s *Scheduler) MustScheduleJobs(confs []JobConf, handlers map[string]func(ctx context.Context) error,
+	opts ...JobOption) []ScheduledJob {
+	jobs, err := s.ScheduleJobs(confs, handlers, opts...)
+	logx.Must(err)
+	return jobs
+}
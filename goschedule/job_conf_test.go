@@ -0,0 +1,138 @@
+package goschedule
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/zeromicro/go-zero/core/conf"
+)
+
+func loadJobConfs(t *testing.T, content string) []JobConf {
+	file := filepath.Join(t.TempDir(), "jobs.yaml")
+	if err := os.WriteFile(file, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	var c struct {
+		Jobs []JobConf
+	}
+	if err := conf.Load(file, &c); err != nil {
+		t.Fatal(err)
+	}
+	return c.Jobs
+}
+
+func TestScheduleJobs(t *testing.T) {
+	confs := loadJobConfs(t, `
+Jobs:
+  - ID: test-conf-report
+    Rule: 0 0 2 * * *
+    TimeZone: Asia/Shanghai
+    Timeout: 10m
+    Misfire: all
+  - ID: test-conf-cleanup
+    Handler: cleanup
+    Rule: "@every 1h"
+  - ID: test-conf-disabled
+    Handler: cleanup
+    Rule: "@every 1m"
+    Enabled: false
+`)
+	if assert.Len(t, confs, 3) {
+		assert.Equal(t, JobConf{
+			ID:             "test-conf-report",
+			Rule:           "0 0 2 * * *",
+			TimeZone:       "Asia/Shanghai",
+			Timeout:        10 * time.Minute,
+			Enabled:        true,
+			Misfire:        "all",
+			MisfireMaxRuns: 10,
+		}, confs[0])
+		assert.Equal(t, "skip", confs[1].Misfire)
+		assert.True(t, confs[1].Enabled)
+		assert.False(t, confs[2].Enabled)
+	}
+
+	handler := func(context.Context) error { return nil }
+	s := newTestScheduler(t)
+	jobs, err := s.ScheduleJobs(confs, map[string]func(ctx context.Context) error{
+		"test-conf-report": handler,
+		"cleanup":          handler,
+	})
+	assert.NoError(t, err)
+	assert.Len(t, jobs, 2)
+
+	infos, err := s.Jobs(context.Background())
+	assert.NoError(t, err)
+	if assert.Len(t, infos, 2) {
+		assert.Equal(t, "test-conf-cleanup", infos[0].ID)
+		assert.Equal(t, "test-conf-report", infos[1].ID)
+		assert.Equal(t, 2, infos[1].NextRun.In(jobs[0].job.parsed.location).Hour())
+	}
+	assert.Equal(t, MisfireRunAll, jobs[0].job.options.misfirePolicy)
+	assert.Equal(t, 10*time.Minute, jobs[0].job.options.timeout)
+}
+
+func TestScheduleJobsInvalid(t *testing.T) {
+	handlers := map[string]func(ctx context.Context) error{
+		"report": func(context.Context) error { return nil },
+	}
+	tests := map[string]JobConf{
+		"unknown handler":   {ID: "test-conf-unknown", Rule: "* * * * * *"},
+		"invalid cron":      {ID: "test-conf-invalid", Handler: "report", Rule: "* * * *"},
+		"invalid time zone": {ID: "test-conf-invalid", Handler: "report", Rule: "* * * * * *", TimeZone: "Mars/Olympus_Mons"},
+		"no ID":             {Handler: "report", Rule: "* * * * * *"},
+	}
+	for name, c := range tests {
+		t.Run(name, func(t *testing.T) {
+			s := newTestScheduler(t)
+			valid := JobConf{ID: "test-conf-valid", Handler: "report", Rule: "* * * * * *", Enabled: true}
+			c.Enabled = true
+			_, err := s.ScheduleJobs([]JobConf{valid, c}, handlers)
+			assert.Error(t, err)
+
+			// Nothing is scheduled.
+			infos, err := s.Jobs(context.Background())
+			assert.NoError(t, err)
+			assert.Empty(t, infos)
+		})
+	}
+
+	s := newTestScheduler(t)
+	c := JobConf{ID: "test-conf-twice", Handler: "report", Rule: "* * * * * *", Enabled: true}
+	_, err := s.ScheduleJobs([]JobConf{c, c}, handlers)
+	assert.ErrorContains(t, err, "configured twice")
+}
+
+func TestScheduleJobsRollback(t *testing.T) {
+	handlers := map[string]func(ctx context.Context) error{
+		"report": func(context.Context) error { return nil },
+	}
+	syncMgr := NewSynchronizationManagerMemory()
+	clockKey := "clock:test-conf-rollback:" + yearlyRule
+	syncMgr.Set(clockKey, 42)
+	s := newTestScheduler(t, WithSynchronizationManager(syncMgr))
+	_, err := s.ScheduleSynchronizedJob("test-conf-existing", yearlyRule, func() {})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = s.ScheduleJobs([]JobConf{
+		{ID: "test-conf-rollback", Handler: "report", Rule: yearlyRule, Enabled: true},
+		{ID: "test-conf-existing", Handler: "report", Rule: yearlyRule, Enabled: true},
+	}, handlers)
+	assert.ErrorIs(t, err, ErrJobExists)
+
+	// The scheduled job is removed, but its clock is kept for the other nodes.
+	infos, err := s.Jobs(context.Background())
+	assert.NoError(t, err)
+	if assert.Len(t, infos, 1) {
+		assert.Equal(t, "test-conf-existing", infos[0].ID)
+	}
+	value, ok := syncMgr.Get(clockKey)
+	assert.True(t, ok)
+	assert.Equal(t, int64(42), value)
+}
@@ -0,0 +1,243 @@
+package goschedule
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+var (
+	// ErrJobNotFound means there is no job of the ID on the Scheduler.
+	ErrJobNotFound = errors.New("goschedule: job not found")
+	// ErrJobTriggered means the job has been triggered by another request, whose run has not finished yet.
+	ErrJobTriggered = errors.New("goschedule: job has been triggered")
+	// ErrJobRunning means a job in lease mode can not be triggered while a node holds its lease.
+	ErrJobRunning = errors.New("goschedule: job is running")
+)
+
+// triggerLockTTL is how long the trigger lock of a job lasts unless it is renewed by the triggered run.
+const triggerLockTTL = 30 * time.Second
+
+// JobInfo describes a job scheduled by a Scheduler.
+type JobInfo struct {
+	ID      string    `json:"id"`
+	Rule    string    `json:"rule"`
+	NextRun time.Time `json:"next_run"`
+	// Paused is true if the job has been paused on any node of the cluster.
+	Paused bool `json:"paused"`
+	// LastRun is the latest record of the job which is not skipped.
+	// It is nil if the Scheduler has no RunRecorder or the job has not run yet. Unless the RunRecorder is
+	// a LastRunRecorder, it is only searched in the latest records, so it may be nil on a large cluster,
+	// whose nodes record a skip of every tick they lose.
+	LastRun *RunRecord `json:"last_run,omitempty"`
+}
+
+// lastRunsToSearch is how many records are searched for the latest one which is not skipped,
+// if the RunRecorder is not a LastRunRecorder.
+const lastRunsToSearch = 20
+
+// Jobs returns the jobs of the Scheduler, ordered by ID.
+func (s *Scheduler) Jobs(ctx context.Context) ([]JobInfo, error) {
+	s.mu.Lock()
+	jobs := make([]*synchronizedJob, 0, len(s.jobs))
+	for _, j := range s.jobs {
+		jobs = append(jobs, j)
+	}
+	s.mu.Unlock()
+	slices.SortFunc(jobs, func(a, b *synchronizedJob) int {
+		return strings.Compare(a.id, b.id)
+	})
+
+	infos := make([]JobInfo, 0, len(jobs))
+	for _, j := range jobs {
+		info := JobInfo{ID: j.id, Rule: j.rule}
+		info.NextRun = j.nextScheduled()
+
+		var err error
+		info.Paused, err = s.synchronizationManager.ExistsCtx(ctx, j.pausedKey())
+		if err != nil {
+			return nil, err
+		}
+
+		info.LastRun, err = s.lastRun(ctx, j.id)
+		if err != nil {
+			return nil, err
+		}
+
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
+
+// lastRun returns the latest record of a job which is not skipped, or nil if there is none.
+func (s *Scheduler) lastRun(ctx context.Context, jobID string) (*RunRecord, error) {
+	if recorder, ok := s.runRecorder.(LastRunRecorder); ok {
+		return recorder.LastRun(ctx, jobID)
+	}
+	records, err := s.LastRuns(ctx, jobID, lastRunsToSearch)
+	if err != nil {
+		return nil, err
+	}
+	for _, record := range records {
+		if !record.Skipped {
+			return &record, nil
+		}
+	}
+	return nil, nil
+}
+
+// Pause stops the job from running on every node of the cluster, until it is resumed.
+// The ticks of a paused job are still won and skipped, so that they are not caught up after resuming.
+func (s *Scheduler) Pause(ctx context.Context, jobID string) error {
+	j, err := s.getJob(jobID)
+	if err != nil {
+		return err
+	}
+	return s.synchronizationManager.SetCtx(ctx, j.pausedKey(), s.clock.Now().UnixMilli())
+}
+
+// Resume lets a paused job run again.
+func (s *Scheduler) Resume(ctx context.Context, jobID string) error {
+	j, err := s.getJob(jobID)
+	if err != nil {
+		return err
+	}
+	return s.synchronizationManager.DeleteCtx(ctx, j.pausedKey())
+}
+
+// Trigger runs the job once on this node in the background, whether it is paused or not.
+// All shards of a sharded job run on this node.
+// A job in lease mode holds its lease for the triggered run, so it fails with ErrJobRunning while
+// another run holds one. Any other job holds a trigger lock for the run, so it fails with ErrJobTriggered
+// until the triggered run has finished. If the SynchronizationManager is not a LeaseManager,
+// only the triggers of the same second are deduplicated across the cluster.
+// A job which is not sharded fails with ErrConcurrencyLimit if this node has no slot to run it.
+func (s *Scheduler) Trigger(ctx context.Context, jobID string) error {
+	j, err := s.getJob(jobID)
+	if err != nil {
+		return err
+	}
+
+	if j.membershipManager != nil {
+		run, err := s.trigger(ctx, j, j.runAllShards)
+		if err != nil {
+			return err
+		}
+		go run()
+		return nil
+	}
+
+	if !s.tryAcquireSlot() {
+		return ErrConcurrencyLimit
+	}
+	run, err := s.trigger(ctx, j, j.run)
+	if err != nil {
+		s.releaseSlot()
+		return err
+	}
+	go func() {
+		defer s.releaseSlot()
+		run()
+	}()
+	return nil
+}
+
+// trigger claims the trigger of a job, and returns its run, which calls fn while holding
+// the lease of the job in lease mode, or else the trigger lock of the job.
+func (s *Scheduler) trigger(ctx context.Context, j *synchronizedJob,
+	fn func(ctx context.Context, scheduled time.Time)) (func(), error) {
+	now := s.clock.Now()
+	manager, key, ttl, errHeld := j.leaseManager, j.leaseKey, j.options.leaseTTL, ErrJobRunning
+	if manager == nil {
+		var ok bool
+		if manager, ok = s.synchronizationManager.(LeaseManager); !ok {
+			if err := s.dedupeTrigger(ctx, j, now); err != nil {
+				return nil, err
+			}
+			return func() { fn(withTriggered(j.ctx), now) }, nil
+		}
+		key, ttl, errHeld = j.triggerLockKey(), triggerLockTTL, ErrJobTriggered
+	}
+
+	lease := Lease{
+		Owner:     s.nodeID,
+		Tick:      now.UnixMilli(),
+		ExpiresAt: now.Add(ttl).UnixMilli(),
+	}
+	_, acquired, err := manager.AcquireLeaseCtx(ctx, key, lease, now.UnixMilli())
+	if err != nil {
+		return nil, err
+	}
+	if !acquired {
+		return nil, errHeld
+	}
+	logx.Infof("Synchronized job %q is triggered on node %q", j.id, s.nodeID)
+	return func() {
+		j.holdLease(manager, key, ttl, lease, func(ctx context.Context) { fn(withTriggered(ctx), now) })
+	}, nil
+}
+
+type triggeredKey struct{}
+
+// withTriggered marks the run of ctx as triggered rather than scheduled.
+func withTriggered(ctx context.Context) context.Context {
+	return context.WithValue(ctx, triggeredKey{}, true)
+}
+
+// isTriggered reports whether the run of ctx has been triggered.
+func isTriggered(ctx context.Context) bool {
+	triggered, _ := ctx.Value(triggeredKey{}).(bool)
+	return triggered
+}
+
+// dedupeTrigger claims the trigger of the second of now.
+func (s *Scheduler) dedupeTrigger(ctx context.Context, j *synchronizedJob, now time.Time) error {
+	wasSet, err := setGreaterThan(ctx, s.synchronizationManager, j.triggerKey(), now.Unix(), triggerTTL)
+	if err != nil {
+		return err
+	}
+	if !wasSet {
+		return ErrJobTriggered
+	}
+	logx.Infof("Synchronized job %q is triggered on node %q", j.id, s.nodeID)
+	return nil
+}
+
+func (s *Scheduler) getJob(jobID string) (*synchronizedJob, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	j, ok := s.jobs[jobID]
+	if !ok {
+		return nil, ErrJobNotFound
+	}
+	return j, nil
+}
+
+func (j *synchronizedJob) pausedKey() string {
+	return "paused:" + j.id
+}
+
+func (j *synchronizedJob) triggerKey() string {
+	return "trigger:" + j.id
+}
+
+func (j *synchronizedJob) triggerLockKey() string {
+	return "triggerlock:" + j.id
+}
+
+// paused tells whether the job has been paused. A job is not considered paused if the storage fails.
+func (j *synchronizedJob) paused() bool {
+	paused, err := j.scheduler.synchronizationManager.ExistsCtx(j.ctx, j.pausedKey())
+	if err != nil {
+		logx.Errorf("Can not get the paused state of synchronized job %q: %v", j.id, err)
+		return false
+	}
+	if paused {
+		logx.Infof("Synchronized job %q is paused, skip it", j.id)
+	}
+	return paused
+}
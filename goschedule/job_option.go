@@ -0,0 +1,49 @@
+package goschedule
+
+import "time"
+
+// JobOption customizes a synchronized job.
+type JobOption func(*jobOptions)
+
+type jobOptions struct {
+	timeout            time.Duration
+	errorHandler       func(id string, err error)
+	leaseTTL           time.Duration
+	leaseExpiredPolicy LeaseExpiredPolicy
+	misfirePolicy      MisfirePolicy
+	misfireMaxRuns     int
+	epoch              time.Time
+	timeZone           string
+	calendar           Calendar
+	shards             int
+	membershipTTL      time.Duration
+	overlapPolicy      OverlapPolicy
+	retryAttempts      int
+	retryBackoff       time.Duration
+	retryable          func(err error) bool
+	upstreamIDs        []string
+	dependencyTimeout  time.Duration
+}
+
+func newJobOptions(opts []JobOption) jobOptions {
+	var options jobOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+	return options
+}
+
+// WithTimeout limits the duration of each run. The context passed to the job
+// is canceled once the timeout elapses. A zero timeout means no limit.
+func WithTimeout(timeout time.Duration) JobOption {
+	return func(options *jobOptions) {
+		options.timeout = timeout
+	}
+}
+
+// WithErrorHandler registers a hook called with the error returned by a run.
+func WithErrorHandler(handler func(id string, err error)) JobOption {
+	return func(options *jobOptions) {
+		options.errorHandler = handler
+	}
+}
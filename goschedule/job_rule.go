@@ -0,0 +1,175 @@
+package goschedule
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-co-op/gocron/v2"
+	"github.com/robfig/cron/v3"
+)
+
+// The rules of the jobs which are not cron expressions.
+const (
+	// RuleInterval is the prefix of an interval rule, e.g. "@interval 90s". The ticks are aligned
+	// to the epoch of the job, so that all nodes agree on them, whenever they are started.
+	// The ticks of the cron descriptor "@every 90s" follow the start of each node instead.
+	RuleInterval = "@interval "
+	// RuleAt is the prefix of a one-shot rule at an absolute time in RFC 3339, e.g. "@at 2024-10-01T08:00:00+08:00".
+	// A one-shot job scheduled after its time is a missed tick, which only runs
+	// if the MisfirePolicy is not MisfireSkip and no node has run it yet.
+	RuleAt = "@at "
+	// RuleStartup is the rule of a job which runs on one node when the cluster starts.
+	// The nodes started within the same window since the epoch of the job make one startup of
+	// the cluster. The window is 1 minute by default, and can be given as well, e.g. "@startup 5m".
+	RuleStartup = "@startup"
+)
+
+const defaultStartupWindow = time.Minute
+
+type ruleKind int
+
+const (
+	ruleCron ruleKind = iota
+	ruleInterval
+	ruleOneShot
+	ruleStartup
+)
+
+// jobRule is the parsed rule of a synchronized job.
+type jobRule struct {
+	kind ruleKind
+	// schedule returns the ticks of the job. Those of a startup job are the starts of the windows.
+	schedule cron.Schedule
+	// at is the time of a one-shot job.
+	at time.Time
+	// location is the time zone of a cron job.
+	location *time.Location
+	// dst is true for a cron job at fixed hours, whose ticks repeating the wall clock time are suppressed.
+	dst bool
+}
+
+// WithEpoch sets the time which the ticks of an interval job and the windows of a startup job
+// are aligned to. The default is the Unix epoch. The epoch must be within 290 years of now.
+func WithEpoch(epoch time.Time) JobOption {
+	return func(options *jobOptions) {
+		options.epoch = epoch
+	}
+}
+
+// parseRule parses a cron expression, or one of the rules starting with RuleInterval, RuleAt or RuleStartup.
+func parseRule(rule string, location *time.Location, epoch time.Time) (jobRule, error) {
+	if epoch.IsZero() {
+		epoch = time.Unix(0, 0)
+	}
+	switch {
+	case strings.HasPrefix(rule, RuleInterval):
+		every, err := time.ParseDuration(strings.TrimSpace(strings.TrimPrefix(rule, RuleInterval)))
+		if err != nil {
+			return jobRule{}, err
+		}
+		if every <= 0 {
+			return jobRule{}, fmt.Errorf("goschedule: invalid interval of rule %q", rule)
+		}
+		return jobRule{kind: ruleInterval, schedule: intervalSchedule{every: every, epoch: epoch}}, nil
+	case strings.HasPrefix(rule, RuleAt):
+		at, err := time.Parse(time.RFC3339, strings.TrimSpace(strings.TrimPrefix(rule, RuleAt)))
+		if err != nil {
+			return jobRule{}, err
+		}
+		return jobRule{kind: ruleOneShot, schedule: oneShotSchedule{at: at}, at: at}, nil
+	case strings.HasPrefix(rule, RuleStartup):
+		window := defaultStartupWindow
+		if s := strings.TrimSpace(strings.TrimPrefix(rule, RuleStartup)); s != "" {
+			var err error
+			if window, err = time.ParseDuration(s); err != nil {
+				return jobRule{}, err
+			}
+			if window <= 0 {
+				return jobRule{}, fmt.Errorf("goschedule: invalid window of rule %q", rule)
+			}
+		}
+		return jobRule{kind: ruleStartup, schedule: intervalSchedule{every: window, epoch: epoch}}, nil
+	default:
+		schedule, err := parseCronRule(rule, location)
+		if err != nil {
+			return jobRule{}, err
+		}
+		dst := withDST(schedule)
+		return jobRule{kind: ruleCron, schedule: dst, location: location, dst: dst != schedule}, nil
+	}
+}
+
+// definition returns how gocron runs the job, and whether its only tick has been missed.
+func (r jobRule) definition(rule string, now time.Time) (gocron.JobDefinition, []gocron.JobOption, bool) {
+	switch r.kind {
+	case ruleInterval:
+		every := r.schedule.(intervalSchedule).every
+		return gocron.DurationJob(every), []gocron.JobOption{
+			gocron.WithStartAt(gocron.WithStartDateTime(r.schedule.Next(now))),
+		}, false
+	case ruleOneShot:
+		if r.at.After(now) {
+			return gocron.OneTimeJob(gocron.OneTimeJobStartDateTime(r.at)), nil, false
+		}
+		return gocron.OneTimeJob(gocron.OneTimeJobStartImmediately()), nil, true
+	case ruleStartup:
+		return gocron.OneTimeJob(gocron.OneTimeJobStartImmediately()), nil, false
+	default:
+		return gocron.CronJob(withCronTimeZone(rule, r.location), true), nil, false
+	}
+}
+
+// tick returns the scheduled time of the current tick at now, given the one known by the job.
+func (r jobRule) tick(scheduled, now time.Time) time.Time {
+	switch r.kind {
+	case ruleOneShot:
+		return r.at
+	case ruleStartup:
+		return r.schedule.(intervalSchedule).prev(now)
+	default:
+		return scheduled
+	}
+}
+
+// clockAfter returns the value of the clock after the tick scheduled at t, which is the next tick,
+// or a millisecond after t if it is the last one.
+func (r jobRule) clockAfter(t time.Time) time.Time {
+	next := r.schedule.Next(t)
+	if next.IsZero() {
+		return t.Add(time.Millisecond)
+	}
+	return next
+}
+
+// intervalSchedule has a tick every interval since the epoch.
+type intervalSchedule struct {
+	every time.Duration
+	epoch time.Time
+}
+
+func (s intervalSchedule) Next(t time.Time) time.Time {
+	return s.prev(t).Add(s.every)
+}
+
+// prev returns the latest tick at or before t.
+func (s intervalSchedule) prev(t time.Time) time.Time {
+	d := t.Sub(s.epoch)
+	n := d / s.every
+	if d%s.every < 0 {
+		n--
+	}
+	return s.epoch.Add(n * s.every)
+}
+
+// oneShotSchedule has a single tick.
+type oneShotSchedule struct {
+	at time.Time
+}
+
+func (s oneShotSchedule) Next(t time.Time) time.Time {
+	if t.Before(s.at) {
+		return s.at
+	}
+	return time.Time{}
+}
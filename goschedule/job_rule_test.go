@@ -0,0 +1,139 @@
+package goschedule
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRule(t *testing.T) {
+	epoch := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	r, err := parseRule("@interval 90s", time.UTC, epoch)
+	if assert.NoError(t, err) {
+		assert.Equal(t, ruleInterval, r.kind)
+		assert.Equal(t, epoch.Add(90*time.Second), r.schedule.Next(epoch))
+		assert.Equal(t, epoch.Add(180*time.Second), r.schedule.Next(epoch.Add(100*time.Second)))
+		assert.Equal(t, epoch, r.schedule.Next(epoch.Add(-time.Second)))
+		assert.Equal(t, epoch.Add(-90*time.Second), r.schedule.Next(epoch.Add(-100*time.Second)))
+	}
+
+	at := epoch.Add(time.Hour)
+	r, err = parseRule("@at "+at.Format(time.RFC3339), time.UTC, epoch)
+	if assert.NoError(t, err) {
+		assert.Equal(t, ruleOneShot, r.kind)
+		assert.Equal(t, at, r.schedule.Next(epoch))
+		assert.Zero(t, r.schedule.Next(at))
+		assert.Equal(t, at.Add(time.Millisecond), r.clockAfter(at))
+	}
+
+	r, err = parseRule("@startup 5m", time.UTC, epoch)
+	if assert.NoError(t, err) {
+		assert.Equal(t, ruleStartup, r.kind)
+		assert.Equal(t, epoch.Add(5*time.Minute), r.clockAfter(epoch))
+	}
+
+	// The cron descriptor keeps its ticks relative to the start of the node.
+	r, err = parseRule("@every 90s", time.UTC, epoch)
+	if assert.NoError(t, err) {
+		assert.Equal(t, ruleCron, r.kind)
+		assert.Equal(t, epoch.Add(100*time.Second+90*time.Second), r.schedule.Next(epoch.Add(100*time.Second)))
+	}
+
+	r, err = parseRule("0 * * * * *", time.UTC, epoch)
+	if assert.NoError(t, err) {
+		assert.Equal(t, ruleCron, r.kind)
+		assert.Equal(t, epoch.Add(time.Minute), r.clockAfter(epoch))
+	}
+
+	for _, rule := range []string{"@interval 0s", "@interval soon", "@at tomorrow", "@startup -1m", "* * *"} {
+		_, err := parseRule(rule, time.UTC, epoch)
+		assert.Error(t, err, rule)
+	}
+}
+
+// nodeRuns collects the runs of a job scheduled on several nodes.
+type nodeRuns struct {
+	syncMgr SynchronizationManager
+	// done counts the ticks which the nodes have finished.
+	done atomic.Int32
+	mu   sync.Mutex
+	runs []time.Time
+}
+
+func (r *nodeRuns) millis() []int64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	millis := make([]int64, 0, len(r.runs))
+	for _, t := range r.runs {
+		millis = append(millis, t.UnixMilli())
+	}
+	return millis
+}
+
+// scheduleOnNodes schedules a job on 2 nodes sharing clock and a SynchronizationManager, and collects its runs.
+func scheduleOnNodes(t *testing.T, clock clockwork.FakeClock, id, rule string, opts ...JobOption) *nodeRuns {
+	r := &nodeRuns{syncMgr: NewSynchronizationManagerMemory()}
+	for _, nodeID := range []string{"node-a", "node-b"} {
+		s := newTestScheduler(t, WithClock(clock), WithSynchronizationManager(r.syncMgr), WithNodeID(nodeID),
+			WithHooks(countTicks(&r.done)))
+		_, err := s.ScheduleSynchronizedJobContext(id, rule, func(ctx context.Context) error {
+			r.mu.Lock()
+			defer r.mu.Unlock()
+			r.runs = append(r.runs, ScheduledTime(ctx))
+			return nil
+		}, opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return r
+}
+
+func TestScheduleInterval(t *testing.T) {
+	clock := clockwork.NewFakeClockAt(testStart.Add(500 * time.Millisecond))
+	r := scheduleOnNodes(t, clock, "test-interval", "@interval 2s")
+	// The ticks are aligned to the epoch, not to the start of the nodes.
+	advance(t, clock, 1500*time.Millisecond, &r.done, 2)
+	advance(t, clock, 2*time.Second, &r.done, 2)
+	assert.Equal(t, []int64{testStart.Add(2 * time.Second).UnixMilli(), testStart.Add(4 * time.Second).UnixMilli()},
+		r.millis())
+}
+
+func TestScheduleOneShot(t *testing.T) {
+	clock := clockwork.NewFakeClockAt(testStart)
+	at := testStart.Add(2 * time.Second)
+	r := scheduleOnNodes(t, clock, "test-one-shot", "@at "+at.Format(time.RFC3339))
+	advance(t, clock, 2*time.Second, &r.done, 2)
+	assert.Equal(t, []int64{at.UnixMilli()}, r.millis())
+}
+
+func TestScheduleOneShotMissed(t *testing.T) {
+	clock := clockwork.NewFakeClockAt(testStart)
+	at := testStart.Add(-time.Hour)
+	rule := "@at " + at.Format(time.RFC3339)
+
+	// The missed tick is skipped without a run.
+	r := scheduleOnNodes(t, clock, "test-one-shot-skip", rule)
+	assert.Eventually(t, func() bool {
+		skipped, ok := r.syncMgr.Get(skippedKey("test-one-shot-skip"))
+		return ok && skipped == at.UnixMilli()
+	}, time.Second, time.Millisecond)
+	assert.Empty(t, r.millis())
+
+	r = scheduleOnNodes(t, clock, "test-one-shot-run", rule, WithMisfirePolicy(MisfireRunOnce, 0))
+	assert.Eventually(t, func() bool { return r.done.Load() == 2 }, time.Second, time.Millisecond)
+	assert.Equal(t, []int64{at.UnixMilli()}, r.millis())
+}
+
+func TestScheduleStartup(t *testing.T) {
+	clock := clockwork.NewFakeClockAt(testStart.Add(10 * time.Second))
+	r := scheduleOnNodes(t, clock, "test-startup", RuleStartup)
+	assert.Eventually(t, func() bool { return r.done.Load() == 2 }, time.Second, time.Millisecond)
+	assert.Equal(t, []int64{testStart.UnixMilli()}, r.millis())
+}
@@ -0,0 +1,75 @@
+package goschedule
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	// ErrLeaseExpired is the error of a tick whose node has stopped renewing its lease.
+	ErrLeaseExpired = errors.New("goschedule: the lease of the run has expired")
+	// ErrLeaseNotSupported means a job wants a lease but the SynchronizationManager is not a LeaseManager.
+	ErrLeaseNotSupported = errors.New("goschedule: the synchronization manager does not support leases")
+)
+
+// Lease is the claim of a node on a tick of a job which is in progress.
+// A lease is identified by its owner and tick. Times are in unix milliseconds.
+type Lease struct {
+	// Owner is the ID of the node running the tick.
+	Owner string
+	// Tick is the scheduled time of the tick.
+	Tick int64
+	// ExpiresAt is when the lease is considered abandoned unless it is renewed.
+	ExpiresAt int64
+}
+
+// LeaseManager is implemented by the SynchronizationManagers which support the lease mode of jobs.
+type LeaseManager interface {
+	// AcquireLeaseCtx stores lease under key if there is no lease, or the stored one
+	// has expired at now. It returns the stored lease if there is one.
+	AcquireLeaseCtx(ctx context.Context, key string, lease Lease, now int64) (prev Lease, acquired bool, err error)
+	// RenewLeaseCtx updates the expiry of the stored lease if it is still the given one.
+	RenewLeaseCtx(ctx context.Context, key string, lease Lease) (bool, error)
+	// ReleaseLeaseCtx deletes the stored lease if it is still the given one.
+	ReleaseLeaseCtx(ctx context.Context, key string, lease Lease) error
+	// GetLeaseCtx returns the stored lease.
+	GetLeaseCtx(ctx context.Context, key string) (lease Lease, ok bool, err error)
+}
+
+// LeaseExpiredPolicy decides what happens to a tick whose lease has expired,
+// usually because the node running it has crashed.
+type LeaseExpiredPolicy int
+
+const (
+	// LeaseExpiredRerun makes another node run the tick again.
+	LeaseExpiredRerun LeaseExpiredPolicy = iota
+	// LeaseExpiredFail makes another node mark the tick as failed with ErrLeaseExpired.
+	LeaseExpiredFail
+	// LeaseExpiredSkip leaves the tick, which is neither run again nor failed. The next ticks run as usual.
+	LeaseExpiredSkip
+)
+
+// WithLease runs the job in lease mode. The node winning a tick holds a lease for it,
+// and renews the lease every ttl/3 while the job is running. Every node checks
+// the lease every ttl/2, and takes over the tick once the lease has expired.
+// The SynchronizationManager of the Scheduler must be a LeaseManager.
+func WithLease(ttl time.Duration, policy LeaseExpiredPolicy) JobOption {
+	return func(options *jobOptions) {
+		options.leaseTTL = ttl
+		options.leaseExpiredPolicy = policy
+	}
+}
+
+type scheduledTimeKey struct{}
+
+// ScheduledTime returns the time when the run of the job was scheduled.
+// A run taking over an expired lease has the scheduled time of the original run.
+func ScheduledTime(ctx context.Context) time.Time {
+	t, _ := ctx.Value(scheduledTimeKey{}).(time.Time)
+	return t
+}
+
+func withScheduledTime(ctx context.Context, t time.Time) context.Context {
+	return context.WithValue(ctx, scheduledTimeKey{}, t)
+}
@@ -0,0 +1,200 @@
+package goschedule
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+	"github.com/stretchr/testify/assert"
+	"github.com/zeromicro/go-zero/core/stores/redis"
+)
+
+// testLeaseManager is the contract every LeaseManager must fulfill.
+func testLeaseManager(t *testing.T, leaseManager LeaseManager) {
+	ctx := context.Background()
+	first := Lease{Owner: "node-a", Tick: 1000, ExpiresAt: 2000}
+
+	_, acquired, err := leaseManager.AcquireLeaseCtx(ctx, "lease", first, 1000)
+	assert.NoError(t, err)
+	assert.True(t, acquired)
+
+	prev, acquired, err := leaseManager.AcquireLeaseCtx(ctx, "lease", Lease{Owner: "node-b", Tick: 1000, ExpiresAt: 2500}, 1500)
+	assert.NoError(t, err)
+	assert.False(t, acquired)
+	assert.Equal(t, first, prev)
+
+	renewed, err := leaseManager.RenewLeaseCtx(ctx, "lease", Lease{Owner: "node-b", Tick: 1000, ExpiresAt: 3000})
+	assert.NoError(t, err)
+	assert.False(t, renewed)
+
+	renewed, err = leaseManager.RenewLeaseCtx(ctx, "lease", Lease{Owner: "node-a", Tick: 1000, ExpiresAt: 3000})
+	assert.NoError(t, err)
+	assert.True(t, renewed)
+
+	lease, ok, err := leaseManager.GetLeaseCtx(ctx, "lease")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, Lease{Owner: "node-a", Tick: 1000, ExpiresAt: 3000}, lease)
+
+	// The lease of node-a has expired, so node-b takes it over.
+	second := Lease{Owner: "node-b", Tick: 1000, ExpiresAt: 5000}
+	prev, acquired, err = leaseManager.AcquireLeaseCtx(ctx, "lease", second, 3000)
+	assert.NoError(t, err)
+	assert.True(t, acquired)
+	assert.Equal(t, "node-a", prev.Owner)
+
+	// node-a can not renew or release a lease which is no longer its own.
+	renewed, err = leaseManager.RenewLeaseCtx(ctx, "lease", Lease{Owner: "node-a", Tick: 1000, ExpiresAt: 6000})
+	assert.NoError(t, err)
+	assert.False(t, renewed)
+	assert.NoError(t, leaseManager.ReleaseLeaseCtx(ctx, "lease", first))
+	lease, ok, err = leaseManager.GetLeaseCtx(ctx, "lease")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, second, lease)
+
+	assert.NoError(t, leaseManager.ReleaseLeaseCtx(ctx, "lease", second))
+	_, ok, err = leaseManager.GetLeaseCtx(ctx, "lease")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestLeaseManagerMemory(t *testing.T) {
+	testLeaseManager(t, NewSynchronizationManagerMemory().(LeaseManager))
+}
+
+func TestLeaseManagerRedis(t *testing.T) {
+	store := redis.MustNewRedis(redis.RedisConf{Host: "127.0.0.1:6379", Type: "node"})
+	_, _ = store.Del("TestLeaseManagerRedis:lease")
+	testLeaseManager(t, NewSynchronizationManagerRedis(store, "TestLeaseManagerRedis").(LeaseManager))
+}
+
+func TestLeaseNotSupported(t *testing.T) {
+	s := newTestScheduler(t, WithSynchronizationManager(NewSynchronizationManagerXorm(newTestEngine(t), "synchronization")))
+	_, err := s.ScheduleSynchronizedJob("test-lease-not-supported", "* * * * * *", func() {}, WithLease(time.Second, LeaseExpiredRerun))
+	assert.ErrorIs(t, err, ErrLeaseNotSupported)
+}
+
+const yearlyRule = "0 0 0 1 1 *"
+
+func TestLeaseTakeOverRerun(t *testing.T) {
+	clock := clockwork.NewFakeClockAt(testStart)
+	syncMgr := NewSynchronizationManagerMemory()
+	leaseManager := syncMgr.(LeaseManager)
+	leaseKey := "lease:test-lease-take-over-rerun:" + yearlyRule
+	tick := testStart.Add(-time.Minute)
+
+	// A node has crashed while running the tick.
+	_, _, err := leaseManager.AcquireLeaseCtx(context.Background(), leaseKey, Lease{
+		Owner:     "crashed-node",
+		Tick:      tick.UnixMilli(),
+		ExpiresAt: testStart.Add(-time.Second).UnixMilli(),
+	}, testStart.UnixMilli())
+	assert.NoError(t, err)
+
+	reruns := make(chan time.Time, 1)
+	s := newTestScheduler(t, WithClock(clock), WithSynchronizationManager(syncMgr), WithNodeID("node-a"))
+	_, err = s.ScheduleSynchronizedJobContext("test-lease-take-over-rerun", yearlyRule, func(ctx context.Context) error {
+		reruns <- ScheduledTime(ctx)
+		return nil
+	}, WithLease(200*time.Millisecond, LeaseExpiredRerun))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var scheduled time.Time
+	advanceUntil(t, clock, 100*time.Millisecond, func() bool {
+		select {
+		case scheduled = <-reruns:
+			return true
+		default:
+			return false
+		}
+	})
+	assert.True(t, tick.Equal(scheduled))
+
+	assert.Eventually(t, func() bool {
+		_, ok, _ := leaseManager.GetLeaseCtx(context.Background(), leaseKey)
+		return !ok
+	}, time.Second, time.Millisecond)
+}
+
+func TestLeaseTakeOverFail(t *testing.T) {
+	clock := clockwork.NewFakeClockAt(testStart)
+	syncMgr := NewSynchronizationManagerMemory()
+	leaseKey := "lease:test-lease-take-over-fail:" + yearlyRule
+	_, _, err := syncMgr.(LeaseManager).AcquireLeaseCtx(context.Background(), leaseKey, Lease{
+		Owner:     "crashed-node",
+		Tick:      testStart.UnixMilli(),
+		ExpiresAt: testStart.Add(-time.Second).UnixMilli(),
+	}, testStart.UnixMilli())
+	assert.NoError(t, err)
+
+	failures := make(chan error, 1)
+	s := newTestScheduler(t, WithClock(clock), WithSynchronizationManager(syncMgr), WithHooks(Hooks{
+		OnFailure: func(jobID string, err error) { failures <- err },
+	}))
+	_, err = s.ScheduleSynchronizedJob("test-lease-take-over-fail", yearlyRule, func() {
+		t.Error("the tick should not run again")
+	}, WithLease(200*time.Millisecond, LeaseExpiredFail))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var failure error
+	advanceUntil(t, clock, 100*time.Millisecond, func() bool {
+		select {
+		case failure = <-failures:
+			return true
+		default:
+			return false
+		}
+	})
+	assert.ErrorIs(t, failure, ErrLeaseExpired)
+	assert.ErrorContains(t, failure, "crashed-node")
+}
+
+func TestLeaseRenew(t *testing.T) {
+	const id, rule = "test-lease-renew", "* * * * * *"
+	clock := clockwork.NewFakeClockAt(testStart)
+	syncMgr := NewSynchronizationManagerMemory()
+
+	var mu sync.Mutex
+	runs := make(map[time.Time]int)
+	release := make(chan struct{})
+	var done atomic.Int32
+	for _, nodeID := range []string{"node-a", "node-b"} {
+		s := newTestScheduler(t, WithClock(clock), WithSynchronizationManager(syncMgr), WithNodeID(nodeID),
+			WithHooks(countTicks(&done)))
+		// The run takes longer than the TTL, so that it only survives by renewing the lease.
+		_, err := s.ScheduleSynchronizedJobContext(id, rule, func(ctx context.Context) error {
+			mu.Lock()
+			runs[ScheduledTime(ctx).UTC()]++
+			mu.Unlock()
+			<-release
+			return nil
+		}, WithLease(300*time.Millisecond, LeaseExpiredRerun))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	advance(t, clock, time.Second, &done, 1)
+	// The lease is renewed every TTL/3, so the other node never finds it expired.
+	for range 18 {
+		clock.Advance(50 * time.Millisecond)
+		assert.Eventually(t, func() bool {
+			lease, ok, _ := syncMgr.(LeaseManager).GetLeaseCtx(context.Background(), "lease:"+id+":"+rule)
+			return ok && lease.ExpiresAt >= clock.Now().Add(200*time.Millisecond).UnixMilli()
+		}, time.Second, time.Millisecond)
+	}
+	close(release)
+	assert.Eventually(t, func() bool { return done.Load() == 2 }, time.Second, time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, map[time.Time]int{testStart.Add(time.Second): 1}, runs)
+}
@@ -0,0 +1,41 @@
+package goschedule
+
+import "github.com/zeromicro/go-zero/core/metric"
+
+const metricNamespace = "goschedule"
+
+// The metrics are only collected once prometheus is enabled, e.g. by the Prometheus section of service.ServiceConf.
+var (
+	metricRunsWon = metric.NewCounterVec(&metric.CounterVecOpts{
+		Namespace: metricNamespace,
+		Subsystem: "runs",
+		Name:      "won_total",
+		Help:      "synchronized job runs won by this node.",
+		Labels:    []string{"job"},
+	})
+
+	metricRunsSkipped = metric.NewCounterVec(&metric.CounterVecOpts{
+		Namespace: metricNamespace,
+		Subsystem: "runs",
+		Name:      "skipped_total",
+		Help:      "synchronized job runs skipped by this node.",
+		Labels:    []string{"job"},
+	})
+
+	metricRunsFailed = metric.NewCounterVec(&metric.CounterVecOpts{
+		Namespace: metricNamespace,
+		Subsystem: "runs",
+		Name:      "failed_total",
+		Help:      "synchronized job runs failed on this node.",
+		Labels:    []string{"job"},
+	})
+
+	metricRunDuration = metric.NewHistogramVec(&metric.HistogramVecOpts{
+		Namespace: metricNamespace,
+		Subsystem: "runs",
+		Name:      "duration_ms",
+		Help:      "synchronized job runs duration(ms).",
+		Labels:    []string{"job"},
+		Buckets:   []float64{10, 50, 100, 500, 1000, 5000, 10000, 30000, 60000, 300000},
+	})
+)
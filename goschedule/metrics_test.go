@@ -0,0 +1,74 @@
+package goschedule
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+	prom "github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/zeromicro/go-zero/core/prometheus"
+	"github.com/zeromicro/go-zero/core/trace/tracetest"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// gatherCounter returns the value of a counter of the default prometheus registry, labelled by job.
+func gatherCounter(t *testing.T, name, job string) float64 {
+	families, err := prom.DefaultGatherer.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+		for _, m := range family.GetMetric() {
+			for _, label := range m.GetLabel() {
+				if label.GetName() == "job" && label.GetValue() == job {
+					return m.GetCounter().GetValue()
+				}
+			}
+		}
+	}
+	return 0
+}
+
+func TestMetricsAndSpans(t *testing.T) {
+	prometheus.Enable()
+	exporter := tracetest.NewInMemoryExporter(t)
+
+	clock := clockwork.NewFakeClockAt(testStart)
+	syncMgr := NewSynchronizationManagerXorm(newTestEngine(t), "synchronization")
+	var done atomic.Int32
+	for _, nodeID := range []string{"node-a", "node-b"} {
+		s := newTestScheduler(t, WithClock(clock), WithSynchronizationManager(syncMgr), WithNodeID(nodeID),
+			WithHooks(countTicks(&done)))
+		_, err := s.ScheduleSynchronizedJobContext("test-metrics", "* * * * * *", func(context.Context) error {
+			return errors.New("boom")
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	won := gatherCounter(t, "goschedule_runs_won_total", "test-metrics")
+	skipped := gatherCounter(t, "goschedule_runs_skipped_total", "test-metrics")
+	failed := gatherCounter(t, "goschedule_runs_failed_total", "test-metrics")
+	advance(t, clock, time.Second, &done, 2)
+	advance(t, clock, time.Second, &done, 2)
+
+	assert.Equal(t, won+2, gatherCounter(t, "goschedule_runs_won_total", "test-metrics"))
+	assert.Equal(t, skipped+2, gatherCounter(t, "goschedule_runs_skipped_total", "test-metrics"))
+	assert.Equal(t, failed+2, gatherCounter(t, "goschedule_runs_failed_total", "test-metrics"))
+
+	assert.Eventually(t, func() bool { return len(exporter.GetSpans()) >= 2 }, time.Second, time.Millisecond)
+	spans := exporter.GetSpans()
+	if assert.NotEmpty(t, spans) {
+		assert.Equal(t, "test-metrics", spans[0].Name)
+		assert.Equal(t, codes.Error, spans[0].Status.Code)
+		assert.True(t, spans[0].SpanContext.HasTraceID())
+	}
+}
@@ -0,0 +1,121 @@
+package goschedule
+
+import (
+	"strings"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+// MisfirePolicy decides what happens to the ticks which are missed by all nodes,
+// e.g. when the whole cluster is down. The missed ticks are detected when the job
+// is started, from the clock stored in the SynchronizationManager.
+type MisfirePolicy int
+
+const (
+	// MisfireSkip ignores the missed ticks.
+	MisfireSkip MisfirePolicy = iota
+	// MisfireRunOnce runs the latest missed tick.
+	MisfireRunOnce
+	// MisfireRunAll runs the missed ticks in order, but at most the latest maxRuns of them.
+	MisfireRunAll
+)
+
+// WithMisfirePolicy sets the MisfirePolicy of the job. The default is MisfireSkip.
+// maxRuns is only used by MisfireRunAll. Catch-up runs are synchronized as usual,
+// and ScheduledTime of their context returns the time when they were scheduled.
+// The regular ticks of the job wait until the catch-up finishes.
+// The clocks kept by an ExpiringSynchronizationManager expire ten intervals after the latest tick,
+// but at least a day, so the ticks missed for longer than that are not caught up.
+func WithMisfirePolicy(policy MisfirePolicy, maxRuns int) JobOption {
+	return func(options *jobOptions) {
+		options.misfirePolicy = policy
+		options.misfireMaxRuns = maxRuns
+	}
+}
+
+var cronParser = cron.NewParser(cron.SecondOptional | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+
+// parseCronRule parses rule the same way as gocron.CronJob(rule, true).
+func parseCronRule(rule string, location *time.Location) (cron.Schedule, error) {
+	return cronParser.Parse(withCronTimeZone(rule, location))
+}
+
+// withCronTimeZone prefixes rule with the time zone, unless it has one.
+func withCronTimeZone(rule string, location *time.Location) string {
+	if strings.HasPrefix(rule, "TZ=") || strings.HasPrefix(rule, "CRON_TZ=") {
+		return rule
+	}
+	return "CRON_TZ=" + location.String() + " " + rule
+}
+
+// missedTicks returns the ticks of schedule in [from, until), but only the latest limit of them,
+// and how many ticks there are in total.
+func missedTicks(schedule cron.Schedule, from, until time.Time, limit int) (ticks []time.Time, total int) {
+	for t := from; !t.IsZero() && t.Before(until); t = schedule.Next(t) {
+		total++
+		if limit <= 0 {
+			continue
+		}
+		if len(ticks) == limit {
+			ticks = append(ticks[:0], ticks[1:]...)
+		}
+		ticks = append(ticks, t)
+	}
+	return
+}
+
+// catchUp runs the ticks missed by all nodes according to the MisfirePolicy of the job.
+func (j *synchronizedJob) catchUp() {
+	// A missed one-shot job is run by gocron, and a startup job has no ticks to miss.
+	// The shards of the missed ticks of a sharded job are not assigned to any node.
+	if j.parsed.kind == ruleOneShot || j.parsed.kind == ruleStartup || j.membershipManager != nil {
+		return
+	}
+
+	var limit int
+	switch j.options.misfirePolicy {
+	case MisfireRunOnce:
+		limit = 1
+	case MisfireRunAll:
+		limit = j.options.misfireMaxRuns
+	default:
+		return
+	}
+
+	// The clock holds the scheduled time of the tick after the latest won one.
+	from, ok, err := j.clock.GetCtx(j.ctx)
+	if err != nil {
+		logx.Errorf("Can not get synchronized clock of job %q: %v", j.id, err)
+		return
+	}
+	if !ok {
+		return
+	}
+
+	ticks, total := missedTicks(j.schedule, from, j.scheduler.clock.Now(), limit)
+	if total == 0 {
+		return
+	}
+	logx.Infof("Synchronized job %q has missed %d ticks since %s, catch up %d of them",
+		j.id, total, from.Format(time.RFC3339), len(ticks))
+
+	for _, tick := range ticks {
+		if j.ctx.Err() != nil {
+			return
+		}
+		if !j.scheduler.acquireSlot(j.ctx) {
+			return
+		}
+		slot := &tickSlot{scheduler: j.scheduler, held: true}
+		if j.setClock(j.parsed.clockAfter(tick)) {
+			if j.paused() {
+				j.recordSkip(tick)
+			} else {
+				j.runTick(tick, slot)
+			}
+		}
+		slot.release()
+	}
+}
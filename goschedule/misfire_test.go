@@ -0,0 +1,147 @@
+package goschedule
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMissedTicks(t *testing.T) {
+	schedule, err := parseCronRule("0 * * * * *", time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	until := from.Add(5*time.Minute + 30*time.Second)
+
+	ticks, total := missedTicks(schedule, from, until, 3)
+	assert.Equal(t, 6, total)
+	assert.Equal(t, []time.Time{from.Add(3 * time.Minute), from.Add(4 * time.Minute), from.Add(5 * time.Minute)}, ticks)
+
+	ticks, total = missedTicks(schedule, from, until, 1)
+	assert.Equal(t, 6, total)
+	assert.Equal(t, []time.Time{from.Add(5 * time.Minute)}, ticks)
+
+	ticks, total = missedTicks(schedule, until, until, 1)
+	assert.Zero(t, total)
+	assert.Empty(t, ticks)
+}
+
+func TestMisfirePolicy(t *testing.T) {
+	const rule = "0 * * * * *"
+	// All nodes have been down for about 5 minutes, and the next regular tick is 30 seconds away.
+	clock := clockwork.NewFakeClockAt(testStart.Add(30 * time.Second))
+	from := testStart.Add(-5 * time.Minute)
+
+	tests := []struct {
+		name   string
+		option JobOption
+		expect []time.Time
+	}{
+		{
+			name:   "Skip",
+			option: WithMisfirePolicy(MisfireSkip, 0),
+			expect: nil,
+		},
+		{
+			name:   "RunOnce",
+			option: WithMisfirePolicy(MisfireRunOnce, 0),
+			expect: []time.Time{from.Add(5 * time.Minute)},
+		},
+		{
+			name:   "RunAll",
+			option: WithMisfirePolicy(MisfireRunAll, 3),
+			expect: []time.Time{from.Add(3 * time.Minute), from.Add(4 * time.Minute), from.Add(5 * time.Minute)},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			syncMgr := NewSynchronizationManagerXorm(newTestEngine(t), "synchronization")
+			syncMgr.Set("clock:test-misfire-policy:"+rule, from.UnixMilli())
+
+			var mu sync.Mutex
+			var runs []time.Time
+			schedule := func(nodeID string) {
+				s := newTestScheduler(t, WithClock(clock), WithSynchronizationManager(syncMgr), WithNodeID(nodeID))
+				job, err := s.ScheduleSynchronizedJobContext("test-misfire-policy", rule, func(ctx context.Context) error {
+					mu.Lock()
+					defer mu.Unlock()
+					runs = append(runs, ScheduledTime(ctx))
+					return nil
+				}, test.option)
+				if err != nil {
+					t.Fatal(err)
+				}
+				select {
+				case <-job.job.caughtUp:
+				case <-time.After(time.Second):
+					t.Fatal("the job does not catch up")
+				}
+			}
+
+			schedule("node-a")
+			// Another node starting later has nothing to catch up.
+			schedule("node-b")
+
+			mu.Lock()
+			defer mu.Unlock()
+			if assert.Len(t, runs, len(test.expect)) {
+				for i := range runs {
+					assert.True(t, test.expect[i].Equal(runs[i]), "expect %v, got %v", test.expect[i], runs[i])
+				}
+			}
+			if len(test.expect) > 0 {
+				value, _ := syncMgr.Get("clock:test-misfire-policy:" + rule)
+				assert.Equal(t, from.Add(6*time.Minute).UnixMilli(), value)
+			}
+		})
+	}
+}
+
+func TestMisfireCatchUpBeforeTicks(t *testing.T) {
+	const rule = "* * * * * *"
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := clockwork.NewFakeClockAt(start)
+	syncMgr := NewSynchronizationManagerMemory()
+	syncMgr.Set("clock:test-misfire-order:"+rule, start.Add(-2*time.Second).UnixMilli())
+
+	running, release := make(chan struct{}), make(chan struct{})
+	var mu sync.Mutex
+	var runs []time.Time
+	s := newTestScheduler(t, WithClock(clock), WithSynchronizationManager(syncMgr))
+	_, err := s.ScheduleSynchronizedJobContext("test-misfire-order", rule, func(ctx context.Context) error {
+		scheduled := ScheduledTime(ctx)
+		if scheduled.Equal(start.Add(-2 * time.Second)) {
+			close(running)
+			<-release
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		runs = append(runs, scheduled.UTC())
+		return nil
+	}, WithMisfirePolicy(MisfireRunAll, 2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ran := func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(runs)
+	}
+
+	// A regular tick fires while the first missed tick is running.
+	<-running
+	clock.Advance(time.Second)
+	assert.Never(t, func() bool { return ran() > 0 }, 100*time.Millisecond, 10*time.Millisecond)
+	close(release)
+	assert.Eventually(t, func() bool { return ran() == 3 }, time.Second, 10*time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []time.Time{start.Add(-2 * time.Second), start.Add(-time.Second), start.Add(time.Second)}, runs)
+}
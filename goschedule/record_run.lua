@@ -0,0 +1,8 @@
+local key = KEYS[1]
+local capacity = tonumber(ARGV[1])
+local record = ARGV[2]
+
+redis.call('LPUSH', key, record)
+redis.call('LTRIM', key, 0, capacity - 1)
+
+return "OK"
@@ -0,0 +1,13 @@
+local key = KEYS[1]
+local owner = ARGV[1]
+local tick = ARGV[2]
+
+local current = redis.call('HMGET', key, 'owner', 'tick')
+
+if current[1] ~= owner or current[2] ~= tick then
+  return 0
+end
+
+redis.call('DEL', key)
+
+return 1
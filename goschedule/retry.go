@@ -0,0 +1,63 @@
+package goschedule
+
+import (
+	"context"
+	"time"
+
+	"github.com/zeromicro/go-zero/core/logx"
+	"github.com/zeromicro/go-zero/core/mathx"
+)
+
+// retryJitter randomizes the delays between the attempts of a run by up to 20%,
+// so that the jobs failing together do not retry together.
+var retryJitter = mathx.NewUnstable(0.2)
+
+// WithRetry retries a failed run on the node which has won the tick, with at most attempts tries in total.
+// The delay before the n-th retry is backoff * 2^(n-1), give or take 20%.
+// Only the errors for which retryable returns true are retried. A nil retryable retries all errors,
+// including the *PanicError of a panic. Every attempt is written to the RunRecorder, but the error handler,
+// OnFailure and the failure metric only see the error of the last attempt. In the lease mode,
+// the lease is held until the last attempt has returned. WithTimeout limits each attempt.
+func WithRetry(attempts int, backoff time.Duration, retryable func(err error) bool) JobOption {
+	return func(options *jobOptions) {
+		options.retryAttempts = attempts
+		options.retryBackoff = backoff
+		options.retryable = retryable
+	}
+}
+
+type attemptKey struct{}
+
+// Attempt returns the attempt of a run, which is 1 for the first one and increases with every retry.
+func Attempt(ctx context.Context) int {
+	attempt, ok := ctx.Value(attemptKey{}).(int)
+	if !ok {
+		return 1
+	}
+	return attempt
+}
+
+func withAttempt(ctx context.Context, attempt int) context.Context {
+	return context.WithValue(ctx, attemptKey{}, attempt)
+}
+
+// retry tells whether the failed attempt of a run is to be retried, and waits for the backoff if so.
+// It returns false if the job is stopped or the run is canceled while waiting.
+func (j *synchronizedJob) retry(ctx context.Context, attempt int, err error) bool {
+	if attempt >= j.options.retryAttempts || ctx.Err() != nil {
+		return false
+	}
+	if j.options.retryable != nil && !j.options.retryable(err) {
+		return false
+	}
+
+	delay := retryJitter.AroundDuration(j.options.retryBackoff << (attempt - 1))
+	logx.WithContext(ctx).Infof("Synchronized job %q will retry in %v, attempt %d of %d",
+		j.id, delay, attempt+1, j.options.retryAttempts)
+	select {
+	case <-ctx.Done():
+		return false
+	case <-j.scheduler.clock.After(delay):
+		return true
+	}
+}
@@ -0,0 +1,92 @@
+package goschedule
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var errTransient = errors.New("transient")
+
+func TestRetry(t *testing.T) {
+	recorder := NewRunRecorderMemory(0)
+	failures := make(chan error, 10)
+	successes := make(chan string, 10)
+	s := newTestScheduler(t, WithRunRecorder(recorder), WithHooks(Hooks{
+		OnSuccess: func(jobID string) { successes <- jobID },
+		OnFailure: func(jobID string, err error) { failures <- err },
+	}))
+
+	var attempts []int
+	_, err := s.ScheduleSynchronizedJobContext("test-retry", yearlyRule, func(ctx context.Context) error {
+		attempts = append(attempts, Attempt(ctx))
+		if len(attempts) < 3 {
+			return errTransient
+		}
+		return nil
+	}, WithRetry(5, 10*time.Millisecond, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NoError(t, s.Trigger(context.Background(), "test-retry"))
+
+	select {
+	case <-successes:
+	case <-time.After(time.Second):
+		t.Fatal("the job does not succeed")
+	}
+	assert.Empty(t, failures)
+	assert.Equal(t, []int{1, 2, 3}, attempts)
+
+	records, err := s.LastRuns(context.Background(), "test-retry", 10)
+	assert.NoError(t, err)
+	if assert.Len(t, records, 3) {
+		assert.Equal(t, 3, records[0].Attempt)
+		assert.Empty(t, records[0].Error)
+		assert.Equal(t, 2, records[1].Attempt)
+		assert.Equal(t, errTransient.Error(), records[1].Error)
+		assert.Equal(t, 1, records[2].Attempt)
+		// The backoff doubles, give or take 20%.
+		assert.GreaterOrEqual(t, records[0].Start.Sub(records[1].End), 16*time.Millisecond)
+		assert.GreaterOrEqual(t, records[1].Start.Sub(records[2].End), 8*time.Millisecond)
+	}
+}
+
+func TestRetryNotRetryable(t *testing.T) {
+	recorder := NewRunRecorderMemory(0)
+	failures := make(chan error, 10)
+	s := newTestScheduler(t, WithRunRecorder(recorder), WithHooks(Hooks{
+		OnFailure: func(jobID string, err error) { failures <- err },
+	}))
+
+	var attempts int
+	permanent := errors.New("permanent")
+	_, err := s.ScheduleSynchronizedJobContext("test-retry-not-retryable", yearlyRule, func(ctx context.Context) error {
+		attempts++
+		if attempts == 1 {
+			return errTransient
+		}
+		return permanent
+	}, WithRetry(3, time.Millisecond, func(err error) bool { return errors.Is(err, errTransient) }))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NoError(t, s.Trigger(context.Background(), "test-retry-not-retryable"))
+
+	// The permanent error is not retried, and only the error of the last attempt is reported.
+	select {
+	case err := <-failures:
+		assert.ErrorIs(t, err, permanent)
+	case <-time.After(time.Second):
+		t.Fatal("the job does not fail")
+	}
+	assert.Empty(t, failures)
+	assert.Equal(t, 2, attempts)
+
+	records, err := s.LastRuns(context.Background(), "test-retry-not-retryable", 10)
+	assert.NoError(t, err)
+	assert.Len(t, records, 2)
+}
@@ -0,0 +1,193 @@
+package goschedule
+
+import (
+	"context"
+	_ "embed"
+	"sync"
+	"time"
+
+	"github.com/zeromicro/go-zero/core/jsonx"
+	"github.com/zeromicro/go-zero/core/stores/redis"
+)
+
+// RunRecord is what happened to a tick of a synchronized job on a node.
+type RunRecord struct {
+	JobID    string        `json:"job_id"`
+	NodeID   string        `json:"node_id"`
+	Start    time.Time     `json:"start"`
+	End      time.Time     `json:"end"`
+	Duration time.Duration `json:"duration"`
+	// Skipped is true if the job did not run on this node, because another node has won the tick, the job is paused,
+	// the previous run is still in progress or the node has reached its concurrency limit.
+	Skipped bool `json:"skipped,omitempty"`
+	// Error is the error returned by the job.
+	Error string `json:"error,omitempty"`
+	// Panic is the value recovered from a panicking job, followed by the stack trace.
+	Panic string `json:"panic,omitempty"`
+	// ShardIndex is the shard of the run of a sharded job.
+	ShardIndex int `json:"shard_index,omitempty"`
+	// ShardCount is the number of shards of a sharded job, or 0 if the job is not sharded.
+	ShardCount int `json:"shard_count,omitempty"`
+	// Attempt is the attempt of the run, starting at 1, or 0 if the job has no retries.
+	Attempt int `json:"attempt,omitempty"`
+}
+
+// RunRecorder keeps the history of the runs of synchronized jobs.
+type RunRecorder interface {
+	// Record writes a RunRecord.
+	Record(ctx context.Context, record RunRecord) error
+	// LastRuns returns at most n of the latest records of a job, the latest first.
+	LastRuns(ctx context.Context, jobID string, n int) ([]RunRecord, error)
+}
+
+// LastRunRecorder is a RunRecorder which keeps the latest record of each job which is not skipped,
+// however many skipped records have been written after it.
+type LastRunRecorder interface {
+	RunRecorder
+	// LastRun returns the latest record of a job which is not skipped, or nil if there is none.
+	LastRun(ctx context.Context, jobID string) (*RunRecord, error)
+}
+
+var _ LastRunRecorder = (*runRecorderMemory)(nil)
+var _ LastRunRecorder = (*runRecorderRedis)(nil)
+
+const defaultRunRecorderCapacity = 100
+
+type runRecorderMemory struct {
+	mu       sync.Mutex
+	capacity int
+	records  map[string][]RunRecord
+	lastRuns map[string]RunRecord
+}
+
+// NewRunRecorderMemory returns a RunRecorder which keeps at most capacity records per job in memory.
+// A capacity <= 0 means the default capacity of 100.
+func NewRunRecorderMemory(capacity int) RunRecorder {
+	if capacity <= 0 {
+		capacity = defaultRunRecorderCapacity
+	}
+	memory := new(runRecorderMemory)
+	memory.capacity = capacity
+	memory.records = make(map[string][]RunRecord)
+	memory.lastRuns = make(map[string]RunRecord)
+	return memory
+}
+
+func (memory *runRecorderMemory) Record(_ context.Context, record RunRecord) error {
+	memory.mu.Lock()
+	defer memory.mu.Unlock()
+
+	records := append(memory.records[record.JobID], record)
+	if len(records) > memory.capacity {
+		records = records[len(records)-memory.capacity:]
+	}
+	memory.records[record.JobID] = records
+	if !record.Skipped {
+		memory.lastRuns[record.JobID] = record
+	}
+	return nil
+}
+
+func (memory *runRecorderMemory) LastRuns(_ context.Context, jobID string, n int) ([]RunRecord, error) {
+	if n <= 0 {
+		return nil, nil
+	}
+
+	memory.mu.Lock()
+	defer memory.mu.Unlock()
+
+	records := memory.records[jobID]
+	n = min(n, len(records))
+	last := make([]RunRecord, 0, n)
+	for i := len(records) - 1; i >= len(records)-n; i-- {
+		last = append(last, records[i])
+	}
+	return last, nil
+}
+
+func (memory *runRecorderMemory) LastRun(_ context.Context, jobID string) (*RunRecord, error) {
+	memory.mu.Lock()
+	defer memory.mu.Unlock()
+
+	record, ok := memory.lastRuns[jobID]
+	if !ok {
+		return nil, nil
+	}
+	return &record, nil
+}
+
+//go:embed record_run.lua
+var recordRunLua string
+var recordRunScript = redis.NewScript(recordRunLua)
+
+type runRecorderRedis struct {
+	namespace string
+	capacity  int
+	store     *redis.Redis
+}
+
+// NewRunRecorderRedis returns a RunRecorder which keeps at most capacity records per job in a redis list.
+// A capacity <= 0 means the default capacity of 100.
+func NewRunRecorderRedis(store *redis.Redis, namespace string, capacity int) RunRecorder {
+	if capacity <= 0 {
+		capacity = defaultRunRecorderCapacity
+	}
+	s := new(runRecorderRedis)
+	s.store = store
+	s.namespace = namespace
+	s.capacity = capacity
+	return s
+}
+
+func (s *runRecorderRedis) getRunsKey(jobID string) string {
+	return s.namespace + ":runs:" + jobID
+}
+
+func (s *runRecorderRedis) getLastRunKey(jobID string) string {
+	return s.namespace + ":lastrun:" + jobID
+}
+
+func (s *runRecorderRedis) Record(ctx context.Context, record RunRecord) error {
+	value, err := jsonx.MarshalToString(record)
+	if err != nil {
+		return err
+	}
+	if !record.Skipped {
+		if err := s.store.SetCtx(ctx, s.getLastRunKey(record.JobID), value); err != nil {
+			return err
+		}
+	}
+	_, err = s.store.ScriptRunCtx(ctx, recordRunScript, []string{s.getRunsKey(record.JobID)}, s.capacity, value)
+	return err
+}
+
+func (s *runRecorderRedis) LastRuns(ctx context.Context, jobID string, n int) ([]RunRecord, error) {
+	if n <= 0 {
+		return nil, nil
+	}
+	values, err := s.store.LrangeCtx(ctx, s.getRunsKey(jobID), 0, n-1)
+	if err != nil {
+		return nil, err
+	}
+	records := make([]RunRecord, 0, len(values))
+	for _, value := range values {
+		var record RunRecord
+		if err := jsonx.UnmarshalFromString(value, &record); err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+func (s *runRecorderRedis) LastRun(ctx context.Context, jobID string) (*RunRecord, error) {
+	value, err := s.store.GetCtx(ctx, s.getLastRunKey(jobID))
+	if err != nil || value == "" {
+		return nil, err
+	}
+	var record RunRecord
+	if err := jsonx.UnmarshalFromString(value, &record); err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
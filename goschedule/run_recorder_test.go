@@ -0,0 +1,140 @@
+package goschedule
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+	"github.com/stretchr/testify/assert"
+	"github.com/zeromicro/go-zero/core/stores/redis"
+)
+
+// testRunRecorder is the contract every RunRecorder must fulfill.
+func testRunRecorder(t *testing.T, recorder RunRecorder, capacity int) {
+	ctx := context.Background()
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	for i := 0; i < capacity+2; i++ {
+		err := recorder.Record(ctx, RunRecord{
+			JobID:    "job",
+			NodeID:   fmt.Sprintf("node-%d", i),
+			Start:    start.Add(time.Duration(i) * time.Second),
+			End:      start.Add(time.Duration(i)*time.Second + time.Millisecond),
+			Duration: time.Millisecond,
+			Error:    fmt.Sprintf("error-%d", i),
+		})
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, recorder.Record(ctx, RunRecord{JobID: "other-job", Skipped: true}))
+
+	records, err := recorder.LastRuns(ctx, "job", 2)
+	assert.NoError(t, err)
+	if assert.Len(t, records, 2) {
+		assert.Equal(t, fmt.Sprintf("node-%d", capacity+1), records[0].NodeID)
+		assert.Equal(t, fmt.Sprintf("error-%d", capacity+1), records[0].Error)
+		assert.Equal(t, time.Millisecond, records[0].Duration)
+		assert.True(t, start.Add(time.Duration(capacity+1)*time.Second).Equal(records[0].Start))
+		assert.Equal(t, fmt.Sprintf("node-%d", capacity), records[1].NodeID)
+	}
+
+	records, err = recorder.LastRuns(ctx, "other-job", 10)
+	assert.NoError(t, err)
+	if assert.Len(t, records, 1) {
+		assert.True(t, records[0].Skipped)
+	}
+
+	records, err = recorder.LastRuns(ctx, "no-such-job", 10)
+	assert.NoError(t, err)
+	assert.Empty(t, records)
+
+	// The last run is kept after the skips of the other nodes have filled the capacity.
+	lastRunRecorder := recorder.(LastRunRecorder)
+	for i := 0; i < capacity+2; i++ {
+		assert.NoError(t, recorder.Record(ctx, RunRecord{JobID: "job", Skipped: true}))
+	}
+	lastRun, err := lastRunRecorder.LastRun(ctx, "job")
+	assert.NoError(t, err)
+	if assert.NotNil(t, lastRun) {
+		assert.Equal(t, fmt.Sprintf("node-%d", capacity+1), lastRun.NodeID)
+	}
+	records, err = recorder.LastRuns(ctx, "job", capacity+2)
+	assert.NoError(t, err)
+	if assert.Len(t, records, capacity) {
+		assert.True(t, records[capacity-1].Skipped)
+	}
+
+	lastRun, err = lastRunRecorder.LastRun(ctx, "other-job")
+	assert.NoError(t, err)
+	assert.Nil(t, lastRun)
+}
+
+func TestRunRecorderMemory(t *testing.T) {
+	recorder := NewRunRecorderMemory(5)
+	testRunRecorder(t, recorder, 5)
+
+	records, err := recorder.LastRuns(context.Background(), "job", 100)
+	assert.NoError(t, err)
+	assert.Len(t, records, 5)
+}
+
+func TestRunRecorderRedis(t *testing.T) {
+	store := redis.MustNewRedis(redis.RedisConf{Host: "127.0.0.1:6379", Type: "node"})
+	_, _ = store.Del("TestRunRecorderRedis:runs:job", "TestRunRecorderRedis:runs:other-job",
+		"TestRunRecorderRedis:lastrun:job")
+	recorder := NewRunRecorderRedis(store, "TestRunRecorderRedis", 5)
+	testRunRecorder(t, recorder, 5)
+
+	records, err := recorder.LastRuns(context.Background(), "job", 100)
+	assert.NoError(t, err)
+	assert.Len(t, records, 5)
+}
+
+func TestRunRecorderXorm(t *testing.T) {
+	recorder := NewRunRecorderXorm(newTestEngine(t), "run_record", 5)
+	testRunRecorder(t, recorder, 5)
+
+	records, err := recorder.LastRuns(context.Background(), "job", 100)
+	assert.NoError(t, err)
+	assert.Len(t, records, 5)
+}
+
+func TestScheduleRunRecorder(t *testing.T) {
+	syncMgr := NewSynchronizationManagerXorm(newTestEngine(t), "synchronization")
+	recorder := NewRunRecorderMemory(0)
+	errJob := errors.New("job failed")
+
+	clock := clockwork.NewFakeClockAt(testStart)
+	var done atomic.Int32
+	for _, nodeID := range []string{"node-a", "node-b"} {
+		s := newTestScheduler(t, WithClock(clock), WithSynchronizationManager(syncMgr), WithRunRecorder(recorder),
+			WithNodeID(nodeID), WithHooks(countTicks(&done)))
+		_, err := s.ScheduleSynchronizedJobContext("test-schedule-run-recorder", "* * * * * *", func(context.Context) error {
+			return errJob
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	advance(t, clock, time.Second, &done, 2)
+	records, err := recorder.LastRuns(context.Background(), "test-schedule-run-recorder", 10)
+	assert.NoError(t, err)
+
+	// One node has run the tick and the other has skipped it.
+	if assert.Len(t, records, 2) {
+		assert.NotEqual(t, records[0].NodeID, records[1].NodeID)
+		assert.NotEqual(t, records[0].Skipped, records[1].Skipped)
+		for _, record := range records {
+			if record.Skipped {
+				assert.Empty(t, record.Error)
+			} else {
+				assert.Equal(t, errJob.Error(), record.Error)
+				assert.False(t, record.End.Before(record.Start))
+			}
+		}
+	}
+}
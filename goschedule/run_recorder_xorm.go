@@ -0,0 +1,149 @@
+package goschedule
+
+import (
+	"context"
+	"time"
+
+	"github.com/zeromicro/go-zero/core/logx"
+	"xorm.io/xorm"
+)
+
+var _ LastRunRecorder = (*runRecorderXorm)(nil)
+
+// runRecordRow is a row of the table used by runRecorderXorm.
+type runRecordRow struct {
+	ID       int64     `xorm:"pk autoincr 'id'"`
+	JobID    string    `xorm:"varchar(255) notnull index 'job_id'"`
+	NodeID   string    `xorm:"varchar(255) notnull 'node_id'"`
+	Start    time.Time `xorm:"notnull 'start'"`
+	End      time.Time `xorm:"notnull 'end'"`
+	Duration int64     `xorm:"notnull 'duration'"`
+	Skipped  bool      `xorm:"notnull 'skipped'"`
+	Error    string    `xorm:"text 'error'"`
+	Panic    string    `xorm:"text 'panic'"`
+
+	ShardIndex int `xorm:"notnull default 0 'shard_index'"`
+	ShardCount int `xorm:"notnull default 0 'shard_count'"`
+	Attempt    int `xorm:"notnull default 0 'attempt'"`
+}
+
+// record returns the RunRecord of row.
+func (row runRecordRow) record() RunRecord {
+	return RunRecord{
+		JobID:    row.JobID,
+		NodeID:   row.NodeID,
+		Start:    row.Start,
+		End:      row.End,
+		Duration: time.Duration(row.Duration),
+		Skipped:  row.Skipped,
+		Error:    row.Error,
+		Panic:    row.Panic,
+
+		ShardIndex: row.ShardIndex,
+		ShardCount: row.ShardCount,
+		Attempt:    row.Attempt,
+	}
+}
+
+type runRecorderXorm struct {
+	engine   *xorm.Engine
+	table    string
+	capacity int
+}
+
+// NewRunRecorderXorm returns a RunRecorder which keeps at most capacity records per job in a SQL table.
+// A capacity <= 0 means the default capacity of 100. The table is created if it does not exist.
+func NewRunRecorderXorm(engine *xorm.Engine, table string, capacity int) RunRecorder {
+	if capacity <= 0 {
+		capacity = defaultRunRecorderCapacity
+	}
+	s := new(runRecorderXorm)
+	s.engine = engine
+	s.table = table
+	s.capacity = capacity
+
+	err := engine.Table(table).Sync(new(runRecordRow))
+	logx.Must(err)
+
+	return s
+}
+
+func (s *runRecorderXorm) Record(ctx context.Context, record RunRecord) error {
+	_, err := s.engine.Context(ctx).Table(s.table).Insert(&runRecordRow{
+		JobID:    record.JobID,
+		NodeID:   record.NodeID,
+		Start:    record.Start,
+		End:      record.End,
+		Duration: int64(record.Duration),
+		Skipped:  record.Skipped,
+		Error:    record.Error,
+		Panic:    record.Panic,
+
+		ShardIndex: record.ShardIndex,
+		ShardCount: record.ShardCount,
+		Attempt:    record.Attempt,
+	})
+	if err != nil {
+		return err
+	}
+	return s.trim(ctx, record.JobID)
+}
+
+// trim deletes the records of a job but the latest capacity ones, and the latest one which is not skipped.
+func (s *runRecorderXorm) trim(ctx context.Context, jobID string) error {
+	jobIDEq := s.engine.Quote("job_id") + " = ?"
+	var ids []int64
+	err := s.engine.Context(ctx).Table(s.table).Cols("id").Where(jobIDEq, jobID).
+		Desc("id").Limit(1, s.capacity).Find(&ids)
+	if err != nil || len(ids) == 0 {
+		return err
+	}
+	lastRun, err := s.lastRunRow(ctx, jobID)
+	if err != nil {
+		return err
+	}
+	session := s.engine.Context(ctx).Table(s.table).Where(jobIDEq, jobID).And(s.engine.Quote("id")+" <= ?", ids[0])
+	if lastRun != nil {
+		session = session.And(s.engine.Quote("id")+" <> ?", lastRun.ID)
+	}
+	_, err = session.Delete(new(runRecordRow))
+	return err
+}
+
+// lastRunRow returns the latest row of a job which is not skipped, or nil if there is none.
+func (s *runRecorderXorm) lastRunRow(ctx context.Context, jobID string) (*runRecordRow, error) {
+	var row runRecordRow
+	ok, err := s.engine.Context(ctx).Table(s.table).Where(s.engine.Quote("job_id")+" = ?", jobID).
+		And(s.engine.Quote("skipped")+" = ?", false).Desc("id").Get(&row)
+	if err != nil || !ok {
+		return nil, err
+	}
+	return &row, nil
+}
+
+func (s *runRecorderXorm) LastRuns(ctx context.Context, jobID string, n int) ([]RunRecord, error) {
+	if n <= 0 {
+		return nil, nil
+	}
+	// The rows beyond the capacity are only kept for LastRun.
+	var rows []runRecordRow
+	err := s.engine.Context(ctx).Table(s.table).Where(s.engine.Quote("job_id")+" = ?", jobID).
+		Desc("id").Limit(min(n, s.capacity)).Find(&rows)
+	if err != nil {
+		return nil, err
+	}
+	records := make([]RunRecord, 0, len(rows))
+	for _, row := range rows {
+		records = append(records, row.record())
+	}
+	return records, nil
+}
+
+func (s *runRecorderXorm) LastRun(ctx context.Context, jobID string) (*RunRecord, error) {
+	row, err := s.lastRunRow(ctx, jobID)
+	if err != nil || row == nil {
+		return nil, err
+	}
+	record := row.record()
+	return &record, nil
+}
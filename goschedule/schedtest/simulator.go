@@ -0,0 +1,350 @@
+// Package schedtest runs goschedule jobs on simulated nodes driven by a fake clock,
+// so that the jobs can be tested offline, fast and without sleeping.
+package schedtest
+
+import (
+	"context"
+	"io"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aclisp/go-zero-side/goschedule"
+	"github.com/jonboulle/clockwork"
+)
+
+// settleTimeout is how long the Simulator waits for the nodes to finish a tick.
+const settleTimeout = time.Second
+
+// NewClock returns a fake clock at start, which goschedule.WithClock injects into a Scheduler.
+// Its time only changes when it is advanced.
+func NewClock(start time.Time) clockwork.FakeClock {
+	return clockwork.NewFakeClockAt(start)
+}
+
+// Run is a call of a job on a node of a Simulator. A run which is retried has a Run for every attempt.
+type Run struct {
+	NodeID string
+	JobID  string
+	// Tick is the scheduled time of the run.
+	Tick time.Time
+	// Err is the error returned by the job.
+	Err error
+}
+
+// Simulator runs a Scheduler for each node of a simulated cluster in the process.
+// The Schedulers share a fake clock and an in-memory SynchronizationManager.
+type Simulator struct {
+	t     testing.TB
+	clock clockwork.FakeClock
+	nodes []*node
+
+	mu   sync.Mutex
+	runs []Run
+	// done counts the ticks of each job which each node has finished, by running or skipping them.
+	done map[nodeJob]int
+	// started and returned count the calls of each job on each node.
+	started  map[nodeJob]int
+	returned map[nodeJob]int
+}
+
+type node struct {
+	id        string
+	scheduler *goschedule.Scheduler
+	stopped   bool
+}
+
+type nodeJob struct {
+	nodeID, jobID string
+}
+
+// NewSimulator starts a Scheduler for each of nodeIDs, at start of the fake clock.
+// opts apply to all Schedulers, but their clock, SynchronizationManager, node ID and Hooks
+// are set by the Simulator. The Schedulers are stopped when the test finishes.
+func NewSimulator(t testing.TB, start time.Time, nodeIDs []string, opts ...goschedule.SchedulerOption) *Simulator {
+	t.Helper()
+	s := &Simulator{
+		t:        t,
+		clock:    NewClock(start),
+		done:     make(map[nodeJob]int),
+		started:  make(map[nodeJob]int),
+		returned: make(map[nodeJob]int),
+	}
+	syncMgr := goschedule.NewSynchronizationManagerMemory()
+	t.Cleanup(func() { _ = syncMgr.(io.Closer).Close() })
+	for _, nodeID := range nodeIDs {
+		n := &node{id: nodeID}
+		scheduler, err := goschedule.NewScheduler(slices.Concat(opts, []goschedule.SchedulerOption{
+			goschedule.WithClock(s.clock),
+			goschedule.WithSynchronizationManager(syncMgr),
+			goschedule.WithNodeID(nodeID),
+			goschedule.WithHooks(goschedule.Hooks{
+				OnSuccess: func(jobID string) { s.finish(nodeID, jobID) },
+				OnFailure: func(jobID string, _ error) { s.finish(nodeID, jobID) },
+				OnSkipped: func(jobID string) { s.finish(nodeID, jobID) },
+			}),
+		})...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		scheduler.StartAsync()
+		t.Cleanup(scheduler.Stop)
+		n.scheduler = scheduler
+		s.nodes = append(s.nodes, n)
+	}
+	return s
+}
+
+func (s *Simulator) finish(nodeID, jobID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.done[nodeJob{nodeID, jobID}]++
+}
+
+// Clock returns the fake clock of the Simulator.
+func (s *Simulator) Clock() clockwork.FakeClock {
+	return s.clock
+}
+
+// Scheduler returns the Scheduler of a node, or nil if there is no such node.
+func (s *Simulator) Scheduler(nodeID string) *goschedule.Scheduler {
+	for _, n := range s.nodes {
+		if n.id == nodeID {
+			return n.scheduler
+		}
+	}
+	return nil
+}
+
+// Schedule schedules a job on every running node, and fails the test on error.
+func (s *Simulator) Schedule(id string, rule string, cb func(ctx context.Context) error, opts ...goschedule.JobOption) {
+	s.t.Helper()
+	for _, n := range s.nodes {
+		if n.stopped {
+			continue
+		}
+		key := nodeJob{n.id, id}
+		_, err := n.scheduler.ScheduleSynchronizedJobContext(id, rule, func(ctx context.Context) error {
+			s.mu.Lock()
+			s.started[key]++
+			s.mu.Unlock()
+
+			err := cb(ctx)
+
+			s.mu.Lock()
+			s.returned[key]++
+			s.runs = append(s.runs, Run{NodeID: n.id, JobID: id, Tick: goschedule.ScheduledTime(ctx), Err: err})
+			s.mu.Unlock()
+			return err
+		}, opts...)
+		if err != nil {
+			s.t.Fatal(err)
+		}
+	}
+}
+
+// StopNode stops the Scheduler of a node, as if the node has crashed or left the cluster.
+func (s *Simulator) StopNode(nodeID string) {
+	for _, n := range s.nodes {
+		if n.id == nodeID {
+			n.scheduler.Stop()
+			n.stopped = true
+		}
+	}
+}
+
+// Next returns the time of the next tick of any job on any running node, or zero if there is none.
+func (s *Simulator) Next() time.Time {
+	var next time.Time
+	for _, due := range s.due() {
+		if next.IsZero() || due.next.Before(next) {
+			next = due.next
+		}
+	}
+	if next.IsZero() {
+		return next
+	}
+	return next.In(s.clock.Now().Location())
+}
+
+type dueJob struct {
+	node  *node
+	jobID string
+	next  time.Time
+}
+
+// due returns the next ticks of the jobs of the running nodes.
+func (s *Simulator) due() []dueJob {
+	s.t.Helper()
+	var jobs []dueJob
+	for _, n := range s.nodes {
+		if n.stopped {
+			continue
+		}
+		infos, err := n.scheduler.Jobs(context.Background())
+		if err != nil {
+			s.t.Fatal(err)
+		}
+		for _, info := range infos {
+			if !info.NextRun.IsZero() {
+				jobs = append(jobs, dueJob{n, info.ID, info.NextRun})
+			}
+		}
+	}
+	return jobs
+}
+
+// Advance moves the clock to the next tick, and waits until every running node has finished it,
+// or has returned from the calls of the job it has started for it. The latter is the case of a run
+// whose retry waits for the clock to be advanced again.
+// It returns the time of the tick, or zero if there is none and the clock is not moved.
+// Ticks producing neither a call nor a skip on a node, e.g. those repeating the wall clock time
+// when the clocks are turned back, make it wait for a second before giving up on them.
+func (s *Simulator) Advance() time.Time {
+	s.t.Helper()
+	next := s.Next()
+	if next.IsZero() {
+		return next
+	}
+
+	var due []dueJob
+	done := make(map[nodeJob]int)
+	started := make(map[nodeJob]int)
+	s.mu.Lock()
+	for _, job := range s.due() {
+		if job.next.Equal(next) {
+			due = append(due, job)
+			key := nodeJob{job.node.id, job.jobID}
+			done[key] = s.done[key]
+			started[key] = s.started[key]
+		}
+	}
+	s.mu.Unlock()
+
+	s.clock.Advance(next.Sub(s.clock.Now()))
+
+	deadline := time.Now().Add(settleTimeout)
+	for _, job := range due {
+		key := nodeJob{job.node.id, job.jobID}
+		for !s.finished(job, done[key], started[key], next) {
+			if time.Now().After(deadline) {
+				s.t.Logf("Job %q has not finished the tick of %s on node %q", job.jobID, next.Format(time.RFC3339), job.node.id)
+				break
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+	return next
+}
+
+// finished reports whether the node has finished the tick of the job, or has returned from its calls,
+// since it had finished done ticks and started started calls.
+func (s *Simulator) finished(job dueJob, done, started int, tick time.Time) bool {
+	key := nodeJob{job.node.id, job.jobID}
+	s.mu.Lock()
+	finished := s.done[key] > done || s.started[key] > started && s.returned[key] == s.started[key]
+	s.mu.Unlock()
+	if !finished {
+		return false
+	}
+
+	// gocron has scheduled the next tick as well.
+	infos, err := job.node.scheduler.Jobs(context.Background())
+	if err != nil {
+		return false
+	}
+	for _, info := range infos {
+		if info.ID == job.jobID {
+			return info.NextRun.IsZero() || info.NextRun.After(tick)
+		}
+	}
+	return true
+}
+
+// AdvanceTo runs the ticks until t one by one, and moves the clock to t.
+func (s *Simulator) AdvanceTo(t time.Time) {
+	s.t.Helper()
+	for next := s.Next(); !next.IsZero() && !next.After(t); next = s.Next() {
+		s.Advance()
+	}
+	if now := s.clock.Now(); t.After(now) {
+		s.clock.Advance(t.Sub(now))
+	}
+}
+
+// Runs returns the runs of a job so far, in the order they have returned.
+func (s *Simulator) Runs(jobID string) []Run {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var runs []Run
+	for _, run := range s.runs {
+		if run.JobID == jobID {
+			runs = append(runs, run)
+		}
+	}
+	return runs
+}
+
+// Ticks returns the distinct ticks of the runs of a job, in ascending order.
+func (s *Simulator) Ticks(jobID string) []time.Time {
+	var ticks []time.Time
+	for _, run := range s.Runs(jobID) {
+		if !slices.ContainsFunc(ticks, run.Tick.Equal) {
+			ticks = append(ticks, run.Tick)
+		}
+	}
+	slices.SortFunc(ticks, time.Time.Compare)
+	return ticks
+}
+
+// Nodes returns the IDs of the nodes which have run the tick of a job, in ascending order.
+func (s *Simulator) Nodes(jobID string, tick time.Time) []string {
+	var nodes []string
+	for _, run := range s.Runs(jobID) {
+		if run.Tick.Equal(tick) && !slices.Contains(nodes, run.NodeID) {
+			nodes = append(nodes, run.NodeID)
+		}
+	}
+	slices.Sort(nodes)
+	return nodes
+}
+
+// AssertRanOnce checks that exactly one node has run each of the ticks of a job.
+func (s *Simulator) AssertRanOnce(jobID string, ticks ...time.Time) bool {
+	s.t.Helper()
+	ok := true
+	for _, tick := range ticks {
+		if nodes := s.Nodes(jobID, tick); len(nodes) != 1 {
+			s.t.Errorf("Tick %s of job %q has run on %d nodes %v, want 1", tick.Format(time.RFC3339), jobID, len(nodes), nodes)
+			ok = false
+		}
+	}
+	return ok
+}
+
+// AssertNotRan checks that no node has run any of the ticks of a job.
+func (s *Simulator) AssertNotRan(jobID string, ticks ...time.Time) bool {
+	s.t.Helper()
+	ok := true
+	for _, tick := range ticks {
+		if nodes := s.Nodes(jobID, tick); len(nodes) != 0 {
+			s.t.Errorf("Tick %s of job %q has run on nodes %v, want none", tick.Format(time.RFC3339), jobID, nodes)
+			ok = false
+		}
+	}
+	return ok
+}
+
+// AssertEveryTickRanOnce checks that exactly one node has run every tick of a job which has run,
+// and that there are want of them.
+func (s *Simulator) AssertEveryTickRanOnce(jobID string, want int) bool {
+	s.t.Helper()
+	ticks := s.Ticks(jobID)
+	ok := s.AssertRanOnce(jobID, ticks...)
+	if len(ticks) != want {
+		s.t.Errorf("Job %q has run %d ticks, want %d", jobID, len(ticks), want)
+		ok = false
+	}
+	return ok
+}
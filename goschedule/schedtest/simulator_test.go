@@ -0,0 +1,66 @@
+package schedtest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aclisp/go-zero-side/goschedule"
+	"github.com/stretchr/testify/assert"
+)
+
+var start = time.Date(2024, 10, 1, 0, 0, 0, 0, time.UTC)
+
+func TestSimulator(t *testing.T) {
+	sim := NewSimulator(t, start, []string{"node-a", "node-b", "node-c"})
+	sim.Schedule("test-every-minute", "0 * * * * *", func(context.Context) error { return nil })
+
+	sim.AdvanceTo(start.Add(10 * time.Minute))
+	assert.Equal(t, start.Add(10*time.Minute), sim.Clock().Now())
+	sim.AssertEveryTickRanOnce("test-every-minute", 10)
+	sim.AssertRanOnce("test-every-minute", start.Add(time.Minute), start.Add(10*time.Minute))
+	sim.AssertNotRan("test-every-minute", start)
+
+	// The ticks keep running once a node has stopped.
+	sim.StopNode("node-a")
+	sim.AdvanceTo(start.Add(20 * time.Minute))
+	sim.AssertEveryTickRanOnce("test-every-minute", 20)
+	for _, tick := range sim.Ticks("test-every-minute")[10:] {
+		assert.NotEqual(t, []string{"node-a"}, sim.Nodes("test-every-minute", tick))
+	}
+}
+
+func TestSimulatorRetry(t *testing.T) {
+	sim := NewSimulator(t, start, []string{"node-a", "node-b"})
+	var failed bool
+	sim.Schedule("test-retry", "0 0 * * * *", func(context.Context) error {
+		if !failed {
+			failed = true
+			return errors.New("transient")
+		}
+		return nil
+	}, goschedule.WithRetry(2, time.Second, nil))
+
+	tick := sim.Advance()
+	assert.Equal(t, start.Add(time.Hour), tick)
+	// Wait for the timers of both nodes and the backoff of the retry.
+	sim.Clock().BlockUntil(3)
+	sim.AdvanceTo(tick.Add(2 * time.Second))
+	assert.Eventually(t, func() bool { return len(sim.Runs("test-retry")) == 2 }, time.Second, time.Millisecond)
+
+	// The retry runs on the node which has won the tick.
+	runs := sim.Runs("test-retry")
+	assert.Error(t, runs[0].Err)
+	assert.NoError(t, runs[1].Err)
+	assert.Equal(t, runs[0].NodeID, runs[1].NodeID)
+	sim.AssertRanOnce("test-retry", tick)
+}
+
+func TestSimulatorNoTicks(t *testing.T) {
+	sim := NewSimulator(t, start, []string{"node-a"})
+	assert.True(t, sim.Advance().IsZero())
+	assert.Equal(t, start, sim.Clock().Now())
+	assert.Nil(t, sim.Scheduler("node-b"))
+	assert.NotNil(t, sim.Scheduler("node-a"))
+}
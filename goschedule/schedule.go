@@ -1,62 +1,76 @@
 package goschedule
 
 import (
-	"github.com/go-co-op/gocron/v2"
-	"github.com/zeromicro/go-zero/core/logx"
+	"context"
+
+	"github.com/zeromicro/go-zero/core/proc"
 )
 
 type ScheduledJob struct {
-	job   gocron.Job
-	clock *SynchronizedClock
+	job *synchronizedJob
 }
 
-var scheduler gocron.Scheduler
-var synchronizationManager SynchronizationManager = NewSynchronizationManagerMemory()
+// defaultScheduler backs the package level functions.
+var defaultScheduler *Scheduler
+
+// shutdownCtx is canceled when the process is shutting down, which in turn
+// cancels the contexts of all running jobs.
+var shutdownCtx, cancelOnShutdown = context.WithCancel(context.Background())
 
 func init() {
-	var err error
-	scheduler, err = gocron.NewScheduler()
-	if err != nil {
-		panic(err)
-	}
-	scheduler.Start()
+	proc.AddShutdownListener(cancelOnShutdown)
+
+	defaultScheduler = MustNewScheduler()
+	defaultScheduler.StartAsync()
 }
 
 func SetSynchronizationManager(syncMgr SynchronizationManager) {
-	synchronizationManager = syncMgr
+	defaultScheduler.SetSynchronizationManager(syncMgr)
 }
 
-func ScheduleSynchronizedJob(id string, rule string, cb func()) (ScheduledJob, error) {
-	clock := NewSynchronizedClock(id+":"+rule, synchronizationManager)
-
-	var job gocron.Job
-	var err error
+func ScheduleSynchronizedJob(id string, rule string, cb func(), opts ...JobOption) (ScheduledJob, error) {
+	return defaultScheduler.ScheduleSynchronizedJob(id, rule, cb, opts...)
+}
 
-	job, err = scheduler.NewJob(
-		gocron.CronJob(rule, true),
-		gocron.NewTask(func() {
-			nextTimestamp, err := job.NextRun()
-			if err != nil {
-				logx.Errorf("Can not schedule synchronized job %q: job.NextRun: %v", id, err)
-				return
-			}
+// ScheduleSynchronizedJobContext schedules a context-aware job on the default Scheduler.
+// See [Scheduler.ScheduleSynchronizedJobContext].
+func ScheduleSynchronizedJobContext(id string, rule string, cb func(ctx context.Context) error,
+	opts ...JobOption) (ScheduledJob, error) {
+	return defaultScheduler.ScheduleSynchronizedJobContext(id, rule, cb, opts...)
+}
 
-			wasSet := clock.Set(nextTimestamp)
+// ScheduleShardedJob schedules a sharded job on the default Scheduler.
+// See [Scheduler.ScheduleShardedJob].
+func ScheduleShardedJob(id string, rule string, shardCount int,
+	cb func(ctx context.Context, shardIndex, shardCount int) error, opts ...JobOption) (ScheduledJob, error) {
+	return defaultScheduler.ScheduleShardedJob(id, rule, shardCount, cb, opts...)
+}
 
-			if wasSet {
-				cb()
-			}
-		}),
-	)
-	if err != nil {
-		return ScheduledJob{}, err
-	}
+// ScheduleJobs schedules the configured jobs on the default Scheduler.
+// See [Scheduler.ScheduleJobs].
+func ScheduleJobs(confs []JobConf, handlers map[string]func(ctx context.Context) error,
+	opts ...JobOption) ([]ScheduledJob, error) {
+	return defaultScheduler.ScheduleJobs(confs, handlers, opts...)
+}
 
-	return ScheduledJob{job, clock}, nil
+// MustScheduleJobs is like ScheduleJobs but exits on error.
+func MustScheduleJobs(confs []JobConf, handlers map[string]func(ctx context.Context) error,
+	opts ...JobOption) []ScheduledJob {
+	return defaultScheduler.MustScheduleJobs(confs, handlers, opts...)
 }
 
 func (job ScheduledJob) Stop() {
-	_ = scheduler.RemoveJob(job.job.ID())
+	job.job.stop()
+}
+
+// LastRuns returns at most n of the latest records of the job, the latest first.
+// It returns nothing if the Scheduler has no RunRecorder.
+func (job ScheduledJob) LastRuns(ctx context.Context, n int) ([]RunRecord, error) {
+	return job.job.scheduler.LastRuns(ctx, job.job.id, n)
+}
 
-	job.clock.Reset()
+// ClockFailures returns how many times the job could not set its SynchronizedClock
+// because the SynchronizationManager failed. Retries of the same tick count once.
+func (job ScheduledJob) ClockFailures() uint64 {
+	return job.job.clockFailures.Load()
 }
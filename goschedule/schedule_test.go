@@ -1,26 +1,191 @@
 package goschedule
 
 import (
-	"log"
+	"context"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
-	"github.com/zeromicro/go-zero/core/stores/redis"
+	"github.com/jonboulle/clockwork"
+	"github.com/stretchr/testify/assert"
+	"github.com/zeromicro/go-zero/core/service"
 )
 
 func TestSchedule(t *testing.T) {
-	SetSynchronizationManager(NewSynchronizationManagerRedis(
-		redis.MustNewRedis(redis.RedisConf{Host: "127.0.0.1:6379", Type: "node"}),
-		"TestSchedule",
-	))
+	SetSynchronizationManager(NewSynchronizationManagerMemory())
 
+	runs := make(chan struct{}, 1)
 	job, err := ScheduleSynchronizedJob("test-schedule", "* * * * * *", func() {
-		log.Println("xxxxxxxxx")
+		select {
+		case runs <- struct{}{}:
+		default:
+		}
 	})
 	if err != nil {
 		t.Fatal(err)
 	}
+	defer job.Stop()
 
-	time.Sleep(10 * time.Second)
+	select {
+	case <-runs:
+	case <-time.After(3 * time.Second):
+		t.Fatal("job did not run")
+	}
+}
+
+func newTestScheduler(t *testing.T, opts ...SchedulerOption) *Scheduler {
+	s, err := NewScheduler(opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.StartAsync()
+	t.Cleanup(s.Stop)
+	return s
+}
+
+// testStart is when the fake clocks of the tests start.
+var testStart = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// countTicks returns Hooks counting in done the ticks which a node has finished,
+// by running, failing or skipping them.
+func countTicks(done *atomic.Int32) Hooks {
+	return Hooks{
+		OnSuccess: func(string) { done.Add(1) },
+		OnFailure: func(string, error) { done.Add(1) },
+		OnSkipped: func(string) { done.Add(1) },
+	}
+}
+
+// advance moves clock by d, and waits until done has counted n more ticks.
+func advance(t *testing.T, clock clockwork.FakeClock, d time.Duration, done *atomic.Int32, n int32) {
+	t.Helper()
+	want := done.Load() + n
+	clock.Advance(d)
+	assert.Eventually(t, func() bool { return done.Load() >= want }, time.Second, time.Millisecond)
+}
+
+// advanceUntil advances clock by step until cond holds, giving the jobs a moment after each step,
+// and fails the test after ten steps.
+func advanceUntil(t *testing.T, clock clockwork.FakeClock, step time.Duration, cond func() bool) {
+	t.Helper()
+	for range 10 {
+		clock.Advance(step)
+		if waitFor(cond, 100*time.Millisecond) {
+			return
+		}
+	}
+	t.Fatal("the condition does not hold")
+}
+
+// waitFor reports whether cond holds within timeout.
+func waitFor(cond func() bool, timeout time.Duration) bool {
+	ticker := time.NewTicker(time.Millisecond)
+	defer ticker.Stop()
+	deadline := time.After(timeout)
+	for !cond() {
+		select {
+		case <-deadline:
+			return cond()
+		case <-ticker.C:
+		}
+	}
+	return true
+}
+
+func TestScheduleContext(t *testing.T) {
+	clock := clockwork.NewFakeClockAt(testStart)
+	s := newTestScheduler(t, WithClock(clock))
+
+	errs := make(chan error, 1)
+	job, err := s.ScheduleSynchronizedJobContext("test-schedule-context", "* * * * * *", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}, WithTimeout(100*time.Millisecond), WithErrorHandler(func(id string, err error) {
+		select {
+		case errs <- err:
+		default:
+		}
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer job.Stop()
+	clock.Advance(time.Second)
+
+	select {
+	case err := <-errs:
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	case <-time.After(3 * time.Second):
+		t.Fatal("job did not time out")
+	}
+}
+
+func TestScheduleContextStop(t *testing.T) {
+	clock := clockwork.NewFakeClockAt(testStart)
+	s := newTestScheduler(t, WithClock(clock))
+
+	started := make(chan struct{})
+	errs := make(chan error, 1)
+	job, err := s.ScheduleSynchronizedJobContext("test-schedule-context-stop", "* * * * * *", func(ctx context.Context) error {
+		select {
+		case started <- struct{}{}:
+		default:
+		}
+		<-ctx.Done()
+		return ctx.Err()
+	}, WithErrorHandler(func(id string, err error) {
+		select {
+		case errs <- err:
+		default:
+		}
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	clock.Advance(time.Second)
+
+	select {
+	case <-started:
+	case <-time.After(3 * time.Second):
+		t.Fatal("job did not start")
+	}
 	job.Stop()
+
+	select {
+	case err := <-errs:
+		assert.ErrorIs(t, err, context.Canceled)
+	case <-time.After(time.Second):
+		t.Fatal("job was not canceled")
+	}
+}
+
+func TestSchedulerIndependent(t *testing.T) {
+	var runs [2]atomic.Int32
+	var stopOnce sync.Once
+	group := service.NewServiceGroup()
+	for i := range runs {
+		s, err := NewScheduler(WithSynchronizationManager(NewSynchronizationManagerMemory()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = s.ScheduleSynchronizedJob("test-scheduler-independent", "* * * * * *", func() {
+			runs[i].Add(1)
+			if runs[0].Load() > 0 && runs[1].Load() > 0 {
+				// Stopping waits for the running jobs, this one included.
+				stopOnce.Do(func() { go group.Stop() })
+			}
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		group.Add(s)
+	}
+
+	// Start blocks until the Schedulers are stopped.
+	group.Start()
+
+	for i := range runs {
+		assert.Positive(t, runs[i].Load())
+	}
 }
@@ -0,0 +1,284 @@
+package goschedule
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/go-co-op/gocron/v2"
+	"github.com/jonboulle/clockwork"
+	"github.com/zeromicro/go-zero/core/logx"
+	"github.com/zeromicro/go-zero/core/service"
+	"github.com/zeromicro/go-zero/core/sysx"
+)
+
+var _ service.Service = (*Scheduler)(nil)
+
+// Scheduler runs synchronized jobs. It owns a gocron scheduler and the
+// SynchronizationManager which keeps the jobs from running on multiple nodes.
+// Schedulers are independent of each other, so that one binary can run
+// several of them, e.g. with different redis namespaces.
+type Scheduler struct {
+	scheduler              gocron.Scheduler
+	clock                  clockwork.Clock
+	synchronizationManager ContextSynchronizationManager
+	clockFailurePolicy     ClockFailurePolicy
+	clockRetryAttempts     int
+	clockRetryBackoff      time.Duration
+	nodeID                 string
+	runRecorder            RunRecorder
+	hooks                  Hooks
+	slots                  chan struct{}
+	ctx                    context.Context
+	cancel                 context.CancelFunc
+	stopOnce               sync.Once
+
+	mu      sync.Mutex
+	started bool
+	jobs    map[string]*synchronizedJob
+}
+
+// ErrJobExists means a job of the same ID has been scheduled on the Scheduler.
+var ErrJobExists = errors.New("goschedule: job exists")
+
+// SchedulerOption customizes a Scheduler.
+type SchedulerOption func(*Scheduler)
+
+// WithSynchronizationManager sets the SynchronizationManager of the Scheduler.
+// The default is an in-memory one, which only synchronizes jobs within the process.
+// Errors of the storage are only visible to the Scheduler if syncMgr
+// implements ContextSynchronizationManager as well, as the built-in ones do.
+func WithSynchronizationManager(syncMgr SynchronizationManager) SchedulerOption {
+	return WithContextSynchronizationManager(toContextSynchronizationManager(syncMgr))
+}
+
+// WithContextSynchronizationManager is like WithSynchronizationManager but takes a ContextSynchronizationManager.
+func WithContextSynchronizationManager(syncMgr ContextSynchronizationManager) SchedulerOption {
+	return func(s *Scheduler) {
+		s.synchronizationManager = syncMgr
+	}
+}
+
+// WithNodeID sets the ID of this node, which is written to the RunRecord.
+// The default is the hostname and the process ID.
+func WithNodeID(nodeID string) SchedulerOption {
+	return func(s *Scheduler) {
+		s.nodeID = nodeID
+	}
+}
+
+// WithRunRecorder makes the Scheduler write a RunRecord for every tick of its jobs.
+func WithRunRecorder(recorder RunRecorder) SchedulerOption {
+	return func(s *Scheduler) {
+		s.runRecorder = recorder
+	}
+}
+
+// WithClock sets the clock of the Scheduler, which is the real clock by default.
+// A fake clock lets tests control when the jobs run.
+func WithClock(clock clockwork.Clock) SchedulerOption {
+	return func(s *Scheduler) {
+		s.clock = clock
+	}
+}
+
+// NewScheduler returns a Scheduler which is not started yet.
+func NewScheduler(opts ...SchedulerOption) (*Scheduler, error) {
+	s := new(Scheduler)
+	s.synchronizationManager = toContextSynchronizationManager(NewSynchronizationManagerMemory())
+	s.clockRetryAttempts = defaultClockRetryAttempts
+	s.clockRetryBackoff = defaultClockRetryBackoff
+	s.nodeID = fmt.Sprintf("%s-%d", sysx.Hostname(), os.Getpid())
+	s.clock = clockwork.NewRealClock()
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	var err error
+	s.scheduler, err = gocron.NewScheduler(gocron.WithClock(s.clock))
+	if err != nil {
+		return nil, err
+	}
+
+	s.ctx, s.cancel = context.WithCancel(shutdownCtx)
+	s.jobs = make(map[string]*synchronizedJob)
+	return s, nil
+}
+
+// MustNewScheduler is like NewScheduler but exits on error.
+func MustNewScheduler(opts ...SchedulerOption) *Scheduler {
+	s, err := NewScheduler(opts...)
+	logx.Must(err)
+	return s
+}
+
+// Start starts running the scheduled jobs, and blocks until the Scheduler is stopped
+// or the process is shutting down, so that the Scheduler is a service.Service.
+func (s *Scheduler) Start() {
+	s.StartAsync()
+	<-s.ctx.Done()
+}
+
+// StartAsync is like Start, but returns once the jobs are started.
+// Starting a started or stopped Scheduler does nothing.
+func (s *Scheduler) StartAsync() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.started || s.ctx.Err() != nil {
+		return
+	}
+
+	s.scheduler.Start()
+	s.started = true
+	for _, j := range s.jobs {
+		go j.start()
+	}
+}
+
+// Stop cancels the running jobs and shuts down the Scheduler.
+// A stopped Scheduler can not be started again. Stopping it again does nothing.
+func (s *Scheduler) Stop() {
+	s.stopOnce.Do(func() {
+		s.cancel()
+
+		if err := s.scheduler.Shutdown(); err != nil {
+			logx.Errorf("Can not shutdown scheduler: %v", err)
+		}
+	})
+}
+
+// NodeID returns the ID of this node.
+func (s *Scheduler) NodeID() string {
+	return s.nodeID
+}
+
+// LastRuns returns at most n of the latest records of a job, the latest first.
+// It returns nothing if the Scheduler has no RunRecorder.
+func (s *Scheduler) LastRuns(ctx context.Context, jobID string, n int) ([]RunRecord, error) {
+	if s.runRecorder == nil {
+		return nil, nil
+	}
+	return s.runRecorder.LastRuns(ctx, jobID, n)
+}
+
+// SetSynchronizationManager replaces the SynchronizationManager of the Scheduler.
+// It only affects the jobs scheduled afterwards.
+func (s *Scheduler) SetSynchronizationManager(syncMgr SynchronizationManager) {
+	s.synchronizationManager = toContextSynchronizationManager(syncMgr)
+}
+
+// ScheduleSynchronizedJob schedules a job which runs on one node of the cluster at every tick.
+// rule is a cron expression with optional seconds, or a rule starting with RuleInterval, RuleAt or RuleStartup.
+func (s *Scheduler) ScheduleSynchronizedJob(id string, rule string, cb func(), opts ...JobOption) (ScheduledJob, error) {
+	return s.ScheduleSynchronizedJobContext(id, rule, func(context.Context) error {
+		cb()
+		return nil
+	}, opts...)
+}
+
+// ScheduleSynchronizedJobContext is like ScheduleSynchronizedJob, but the job
+// receives a context which is canceled when the run times out, the job is
+// stopped, the Scheduler is stopped or the process is shutting down.
+// A non-nil error returned by the job is logged and passed to the error handler, if any.
+func (s *Scheduler) ScheduleSynchronizedJobContext(id string, rule string, cb func(ctx context.Context) error,
+	opts ...JobOption) (ScheduledJob, error) {
+	options := newJobOptions(opts)
+	location := time.Local
+	if options.timeZone != "" {
+		var err error
+		if location, err = time.LoadLocation(options.timeZone); err != nil {
+			return ScheduledJob{}, err
+		}
+	}
+	parsed, err := parseRule(rule, location, options.epoch)
+	if err != nil {
+		return ScheduledJob{}, err
+	}
+	if options.calendar != nil {
+		parsed.schedule = calendarSchedule{schedule: parsed.schedule, calendar: options.calendar, location: location}
+	}
+
+	j := &synchronizedJob{
+		id:        id,
+		rule:      rule,
+		cb:        cb,
+		options:   options,
+		parsed:    parsed,
+		schedule:  parsed.schedule,
+		clock:     newSynchronizedClock(id+":"+rule, s.synchronizationManager),
+		scheduler: s,
+		ready:     make(chan struct{}),
+		caughtUp:  make(chan struct{}),
+	}
+	if j.options.overlapPolicy != OverlapAllow && j.options.leaseTTL <= 0 {
+		j.options.leaseTTL = overlapLeaseTTL(j.schedule, s.clock.Now())
+		j.options.leaseExpiredPolicy = LeaseExpiredSkip
+		logx.Infof("Synchronized job %q does not allow overlapping runs, run it in the lease mode with a TTL of %s",
+			id, j.options.leaseTTL)
+	}
+	if j.options.shards > 0 {
+		if j.options.leaseTTL > 0 {
+			return ScheduledJob{}, ErrShardedLease
+		}
+		if len(j.options.upstreamIDs) > 0 {
+			return ScheduledJob{}, ErrShardedDependencies
+		}
+		membershipManager, ok := s.synchronizationManager.(MembershipManager)
+		if !ok {
+			return ScheduledJob{}, ErrMembershipNotSupported
+		}
+		j.membershipManager = membershipManager
+		if j.options.membershipTTL <= 0 {
+			j.options.membershipTTL = defaultMembershipTTL
+		}
+	}
+	if j.options.leaseTTL > 0 {
+		leaseManager, ok := s.synchronizationManager.(LeaseManager)
+		if !ok {
+			return ScheduledJob{}, ErrLeaseNotSupported
+		}
+		j.leaseManager = leaseManager
+		j.leaseKey = "lease:" + id + ":" + rule
+	}
+	j.ctx, j.cancel = context.WithCancel(s.ctx)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.jobs[id]; ok {
+		j.cancel()
+		return ScheduledJob{}, ErrJobExists
+	}
+
+	definition, jobOpts, misfired := parsed.definition(rule, s.clock.Now())
+	j.misfired = misfired
+	j.job, err = s.scheduler.NewJob(definition, gocron.NewTask(j.task), jobOpts...)
+	if err != nil {
+		j.cancel()
+		return ScheduledJob{}, err
+	}
+	next, _ := j.job.NextRun()
+	j.mu.Lock()
+	if j.next.IsZero() {
+		j.next = next
+	}
+	j.mu.Unlock()
+
+	s.jobs[id] = j
+	close(j.ready)
+	if s.started {
+		go j.start()
+	}
+
+	return ScheduledJob{j}, nil
+}
+
+func (s *Scheduler) removeJob(j *synchronizedJob) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.jobs[j.id] == j {
+		delete(s.jobs, j.id)
+	}
+}
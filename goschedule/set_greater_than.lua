@@ -9,6 +9,11 @@ if redis.call("EXISTS", key) == 1 then
   end
 end
 
-redis.call('SET', key, value)
+-- ARGV[2] is the optional TTL of the key in milliseconds.
+if ARGV[2] then
+  redis.call('SET', key, value, 'PX', ARGV[2])
+else
+  redis.call('SET', key, value)
+end
 
 return true
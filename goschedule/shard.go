@@ -0,0 +1,173 @@
+package goschedule
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+var (
+	// ErrMembershipNotSupported means a sharded job is scheduled but the SynchronizationManager is not a MembershipManager.
+	ErrMembershipNotSupported = errors.New("goschedule: the synchronization manager does not support membership")
+	// ErrShardedLease means a sharded job is scheduled in lease mode, which is not supported.
+	ErrShardedLease = errors.New("goschedule: sharded jobs do not support leases")
+)
+
+// MembershipManager is implemented by the SynchronizationManagers which support sharded jobs.
+// Times are in unix milliseconds.
+type MembershipManager interface {
+	// HeartbeatCtx registers the node as a member of the group until expiresAt.
+	HeartbeatCtx(ctx context.Context, group, nodeID string, expiresAt int64) error
+	// LeaveCtx removes the node from the group.
+	LeaveCtx(ctx context.Context, group, nodeID string) error
+	// MembersCtx returns the IDs of the members of the group which have not expired at now, in ascending order.
+	MembersCtx(ctx context.Context, group string, now int64) ([]string, error)
+}
+
+const defaultMembershipTTL = 10 * time.Second
+
+// WithMembershipTTL sets how long a node stays a member of a sharded job after its latest heartbeat.
+// The nodes send heartbeats every ttl/3. The default is 10 seconds.
+func WithMembershipTTL(ttl time.Duration) JobOption {
+	return func(options *jobOptions) {
+		options.membershipTTL = ttl
+	}
+}
+
+type shardKey struct{}
+
+type shard struct {
+	index, count int
+}
+
+func withShard(ctx context.Context, index, count int) context.Context {
+	return context.WithValue(ctx, shardKey{}, shard{index, count})
+}
+
+// Shard returns the shard of a run of a sharded job. count is 0 if the run is not sharded.
+func Shard(ctx context.Context) (index, count int) {
+	s, _ := ctx.Value(shardKey{}).(shard)
+	return s.index, s.count
+}
+
+// ScheduleShardedJob schedules a job whose ticks are split into shardCount shards, which are run by
+// all live nodes of the cluster rather than one. The nodes which have scheduled the job are its members,
+// and the shards are assigned to them in turn, following the order of their IDs. The shards are reassigned
+// on the next tick when nodes join or leave. Every shard of a tick is run once, at most.
+// The SynchronizationManager of the Scheduler must be a MembershipManager.
+func (s *Scheduler) ScheduleShardedJob(id string, rule string, shardCount int,
+	cb func(ctx context.Context, shardIndex, shardCount int) error, opts ...JobOption) (ScheduledJob, error) {
+	if shardCount <= 0 {
+		return ScheduledJob{}, errors.New("goschedule: shard count must be positive")
+	}
+	return s.ScheduleSynchronizedJobContext(id, rule, func(ctx context.Context) error {
+		index, count := Shard(ctx)
+		return cb(ctx, index, count)
+	}, append(opts, withShards(shardCount))...)
+}
+
+func withShards(count int) JobOption {
+	return func(options *jobOptions) {
+		options.shards = count
+	}
+}
+
+// assignShards returns the shards of count which are assigned to nodeID, given the sorted members.
+func assignShards(members []string, nodeID string, count int) []int {
+	var shards []int
+	for i := 0; i < count; i++ {
+		if members[i%len(members)] == nodeID {
+			shards = append(shards, i)
+		}
+	}
+	return shards
+}
+
+func (j *synchronizedJob) shardClock(index int) *SynchronizedClock {
+	return newSynchronizedClock(j.id+":"+j.rule+":shard:"+strconv.Itoa(index), j.scheduler.synchronizationManager)
+}
+
+// runShards runs the shards of a tick which are assigned to this node.
+func (j *synchronizedJob) runShards(scheduled time.Time) {
+	members, err := j.membershipManager.MembersCtx(j.ctx, j.id, j.scheduler.clock.Now().UnixMilli())
+	if err != nil {
+		logx.Errorf("Can not get the members of sharded job %q, skip it: %v", j.id, err)
+		j.skip()
+		return
+	}
+	if len(members) == 0 {
+		logx.Errorf("Sharded job %q has no members, skip it", j.id)
+		j.skip()
+		return
+	}
+
+	shards := assignShards(members, j.scheduler.nodeID, j.options.shards)
+	if len(shards) == 0 {
+		j.skip()
+		return
+	}
+
+	// The shards run in parallel, and every shard is claimed with its own clock,
+	// in case another node has assigned it to itself with a stale list of members.
+	var wg sync.WaitGroup
+	for _, index := range shards {
+		if !j.setClockOf(j.shardClock(index), j.parsed.clockAfter(scheduled)) {
+			continue
+		}
+		wg.Add(1)
+		go func(index int) {
+			defer wg.Done()
+			j.runShard(j.ctx, index, scheduled)
+		}(index)
+	}
+	wg.Wait()
+}
+
+// runAllShards runs all shards of a triggered run on this node.
+func (j *synchronizedJob) runAllShards(ctx context.Context, scheduled time.Time) {
+	var wg sync.WaitGroup
+	for index := 0; index < j.options.shards; index++ {
+		wg.Add(1)
+		go func(index int) {
+			defer wg.Done()
+			j.runShard(ctx, index, scheduled)
+		}(index)
+	}
+	wg.Wait()
+}
+
+// runShard runs a shard once there is a slot for it on this node.
+func (j *synchronizedJob) runShard(ctx context.Context, index int, scheduled time.Time) {
+	if !j.scheduler.acquireSlot(ctx) {
+		return
+	}
+	defer j.scheduler.releaseSlot()
+	j.run(withShard(ctx, index, j.options.shards), scheduled)
+}
+
+// heartbeat keeps this node a member of the sharded job until the job is stopped.
+func (j *synchronizedJob) heartbeat() {
+	ttl := j.options.membershipTTL
+	ticker := j.scheduler.clock.NewTicker(ttl / 3)
+	defer ticker.Stop()
+
+	for {
+		expiresAt := j.scheduler.clock.Now().Add(ttl).UnixMilli()
+		if err := j.membershipManager.HeartbeatCtx(j.ctx, j.id, j.scheduler.nodeID, expiresAt); err != nil {
+			logx.Errorf("Can not send the heartbeat of sharded job %q: %v", j.id, err)
+		}
+
+		select {
+		case <-j.ctx.Done():
+			if err := j.membershipManager.LeaveCtx(context.WithoutCancel(j.ctx), j.id, j.scheduler.nodeID); err != nil {
+				logx.Errorf("Can not leave sharded job %q: %v", j.id, err)
+			}
+			return
+		case <-ticker.Chan():
+		}
+	}
+}
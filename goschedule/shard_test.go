@@ -0,0 +1,158 @@
+package goschedule
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+	"github.com/stretchr/testify/assert"
+	"github.com/zeromicro/go-zero/core/stores/redis"
+)
+
+// testMembershipManager is the contract every MembershipManager must fulfill.
+func testMembershipManager(t *testing.T, membershipManager MembershipManager) {
+	ctx := context.Background()
+
+	members, err := membershipManager.MembersCtx(ctx, "group_%", 1000)
+	assert.NoError(t, err)
+	assert.Empty(t, members)
+
+	assert.NoError(t, membershipManager.HeartbeatCtx(ctx, "group_%", "node-b", 2000))
+	assert.NoError(t, membershipManager.HeartbeatCtx(ctx, "group_%", "node-a", 3000))
+	assert.NoError(t, membershipManager.HeartbeatCtx(ctx, "group_%", "node-c", 3000))
+	assert.NoError(t, membershipManager.HeartbeatCtx(ctx, "group_x", "node-d", 3000))
+	assert.NoError(t, membershipManager.HeartbeatCtx(ctx, "GROUP_%", "node-e", 3000))
+
+	members, err = membershipManager.MembersCtx(ctx, "group_%", 1000)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"node-a", "node-b", "node-c"}, members)
+
+	// node-b has expired, and node-c has left.
+	assert.NoError(t, membershipManager.LeaveCtx(ctx, "group_%", "node-c"))
+	members, err = membershipManager.MembersCtx(ctx, "group_%", 2000)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"node-a"}, members)
+
+	// node-b is back.
+	assert.NoError(t, membershipManager.HeartbeatCtx(ctx, "group_%", "node-b", 4000))
+	members, err = membershipManager.MembersCtx(ctx, "group_%", 2000)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"node-a", "node-b"}, members)
+}
+
+func TestMembershipManagerMemory(t *testing.T) {
+	testMembershipManager(t, NewSynchronizationManagerMemory().(MembershipManager))
+}
+
+func TestMembershipManagerRedis(t *testing.T) {
+	store := redis.MustNewRedis(redis.RedisConf{Host: "127.0.0.1:6379", Type: "node"})
+	_, _ = store.Del("TestMembershipManagerRedis:members:group_%", "TestMembershipManagerRedis:members:group_x",
+		"TestMembershipManagerRedis:members:GROUP_%")
+	testMembershipManager(t, NewSynchronizationManagerRedis(store, "TestMembershipManagerRedis").(MembershipManager))
+}
+
+func TestMembershipManagerXorm(t *testing.T) {
+	syncMgr := NewSynchronizationManagerXorm(newTestEngine(t), "synchronization")
+	testMembershipManager(t, syncMgr.(MembershipManager))
+
+	// The expired members are deleted.
+	assert.True(t, syncMgr.Exists("member:group_%:node-a"))
+	members, err := syncMgr.(MembershipManager).MembersCtx(context.Background(), "group_%", 5000)
+	assert.NoError(t, err)
+	assert.Empty(t, members)
+	assert.False(t, syncMgr.Exists("member:group_%:node-a"))
+	assert.True(t, syncMgr.Exists("member:group_x:node-d"))
+}
+
+func TestAssignShards(t *testing.T) {
+	members := []string{"node-a", "node-b", "node-c"}
+	assert.Equal(t, []int{0, 3}, assignShards(members, "node-a", 5))
+	assert.Equal(t, []int{1, 4}, assignShards(members, "node-b", 5))
+	assert.Equal(t, []int{2}, assignShards(members, "node-c", 5))
+	assert.Empty(t, assignShards(members, "node-d", 5))
+}
+
+func TestScheduleShardedJob(t *testing.T) {
+	const id, shardCount = "test-sharded", 6
+	clock := clockwork.NewFakeClockAt(testStart)
+	syncMgr := NewSynchronizationManagerXorm(newTestEngine(t), "synchronization")
+
+	type run struct {
+		nodeID string
+		tick   time.Time
+		shard  int
+	}
+	var mu sync.Mutex
+	var runs []run
+	var schedulers []*Scheduler
+	for _, nodeID := range []string{"node-a", "node-b", "node-c"} {
+		s := newTestScheduler(t, WithClock(clock), WithSynchronizationManager(syncMgr), WithNodeID(nodeID))
+		schedulers = append(schedulers, s)
+		_, err := s.ScheduleShardedJob(id, "* * * * * *", shardCount,
+			func(ctx context.Context, shardIndex, count int) error {
+				assert.Equal(t, shardCount, count)
+				mu.Lock()
+				defer mu.Unlock()
+				runs = append(runs, run{nodeID, ScheduledTime(ctx).UTC(), shardIndex})
+				return nil
+			})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// waitMembers waits until the job has n members.
+	waitMembers := func(n int) {
+		assert.Eventually(t, func() bool {
+			members, err := syncMgr.(MembershipManager).MembersCtx(context.Background(), id, clock.Now().UnixMilli())
+			return err == nil && len(members) == n
+		}, time.Second, time.Millisecond)
+	}
+	// tick advances the clock to the next tick, and returns the nodes which have run its shards.
+	tick := func() map[string]int {
+		clock.Advance(time.Second)
+		scheduled := clock.Now()
+		shards := make(map[int]string)
+		assert.Eventually(t, func() bool {
+			mu.Lock()
+			defer mu.Unlock()
+			clear(shards)
+			for _, r := range runs {
+				if r.tick.Equal(scheduled) {
+					assert.NotContains(t, shards, r.shard, "shard %d of tick %v runs twice", r.shard, scheduled)
+					shards[r.shard] = r.nodeID
+				}
+			}
+			return len(shards) == shardCount
+		}, time.Second, time.Millisecond)
+		nodes := make(map[string]int)
+		for _, nodeID := range shards {
+			nodes[nodeID]++
+		}
+		return nodes
+	}
+
+	waitMembers(3)
+	assert.Equal(t, map[string]int{"node-a": 2, "node-b": 2, "node-c": 2}, tick())
+	assert.Equal(t, map[string]int{"node-a": 2, "node-b": 2, "node-c": 2}, tick())
+
+	// node-c leaves, and its shards are taken over by the others.
+	schedulers[2].Stop()
+	waitMembers(2)
+	assert.Equal(t, map[string]int{"node-a": 3, "node-b": 3}, tick())
+	assert.Equal(t, map[string]int{"node-a": 3, "node-b": 3}, tick())
+}
+
+func TestShardedJobNotSupported(t *testing.T) {
+	cb := func(context.Context, int, int) error { return nil }
+
+	s := newTestScheduler(t)
+	_, err := s.ScheduleShardedJob("test-sharded-lease", "* * * * * *", 2, cb, WithLease(time.Second, LeaseExpiredRerun))
+	assert.ErrorIs(t, err, ErrShardedLease)
+
+	s = newTestScheduler(t, WithSynchronizationManager(synchronizationManagerAdapter{NewSynchronizationManagerMemory()}))
+	_, err = s.ScheduleShardedJob("test-sharded-not-supported", "* * * * * *", 2, cb)
+	assert.ErrorIs(t, err, ErrMembershipNotSupported)
+}
@@ -1,9 +1,21 @@
 package goschedule
 
 import (
+	"context"
+	"crypto/tls"
 	_ "embed"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"slices"
 	"strconv"
+	"strings"
+	"sync"
+	"time"
 
+	red "github.com/redis/go-redis/v9"
 	"github.com/zeromicro/go-zero/core/logx"
 	"github.com/zeromicro/go-zero/core/stores/redis"
 )
@@ -16,52 +28,390 @@ type SynchronizationManager interface {
 	SetGreaterThan(key string, value int64) bool
 }
 
+// ContextSynchronizationManager is the second version of SynchronizationManager.
+// Its methods take a context and return errors, so that a failure of the storage
+// can be told apart from losing the race to another node.
+type ContextSynchronizationManager interface {
+	SetCtx(ctx context.Context, key string, value int64) error
+	GetCtx(ctx context.Context, key string) (value int64, ok bool, err error)
+	DeleteCtx(ctx context.Context, key string) error
+	ExistsCtx(ctx context.Context, key string) (bool, error)
+	SetGreaterThanCtx(ctx context.Context, key string, value int64) (bool, error)
+}
+
 var _ SynchronizationManager = (*synchronizationManagerMemory)(nil) // Verify that *T implements I.
 var _ SynchronizationManager = (*synchronizationManagerRedis)(nil)
+var _ ContextSynchronizationManager = (*synchronizationManagerMemory)(nil)
+var _ ContextSynchronizationManager = (*synchronizationManagerRedis)(nil)
+var _ ContextSynchronizationManager = synchronizationManagerAdapter{}
+var _ LeaseManager = (*synchronizationManagerMemory)(nil)
+var _ LeaseManager = (*synchronizationManagerRedis)(nil)
+var _ MembershipManager = (*synchronizationManagerMemory)(nil)
+var _ MembershipManager = (*synchronizationManagerRedis)(nil)
+var _ ExpiringSynchronizationManager = (*synchronizationManagerMemory)(nil)
+var _ ExpiringSynchronizationManager = (*synchronizationManagerRedis)(nil)
+var _ Pruner = (*synchronizationManagerMemory)(nil)
+var _ Pruner = (*synchronizationManagerRedis)(nil)
+var _ io.Closer = (*synchronizationManagerMemory)(nil)
+
+// toContextSynchronizationManager returns syncMgr itself if it implements ContextSynchronizationManager,
+// otherwise it wraps syncMgr, which never reports errors.
+func toContextSynchronizationManager(syncMgr SynchronizationManager) ContextSynchronizationManager {
+	if ctxSyncMgr, ok := syncMgr.(ContextSynchronizationManager); ok {
+		return ctxSyncMgr
+	}
+	return synchronizationManagerAdapter{syncMgr}
+}
+
+type synchronizationManagerAdapter struct {
+	SynchronizationManager
+}
+
+func (a synchronizationManagerAdapter) SetCtx(_ context.Context, key string, value int64) error {
+	a.Set(key, value)
+	return nil
+}
+
+func (a synchronizationManagerAdapter) GetCtx(_ context.Context, key string) (int64, bool, error) {
+	value, ok := a.Get(key)
+	return value, ok, nil
+}
+
+func (a synchronizationManagerAdapter) DeleteCtx(_ context.Context, key string) error {
+	a.Delete(key)
+	return nil
+}
+
+func (a synchronizationManagerAdapter) ExistsCtx(_ context.Context, key string) (bool, error) {
+	return a.Exists(key), nil
+}
+
+func (a synchronizationManagerAdapter) SetGreaterThanCtx(_ context.Context, key string, value int64) (bool, error) {
+	return a.SetGreaterThan(key, value), nil
+}
 
 //go:embed set_greater_than.lua
 var setGreaterThanLua string
 var setGreaterThanScript = redis.NewScript(setGreaterThanLua)
 
+var (
+	//go:embed acquire_lease.lua
+	acquireLeaseLua    string
+	acquireLeaseScript = redis.NewScript(acquireLeaseLua)
+	//go:embed renew_lease.lua
+	renewLeaseLua    string
+	renewLeaseScript = redis.NewScript(renewLeaseLua)
+	//go:embed release_lease.lua
+	releaseLeaseLua    string
+	releaseLeaseScript = redis.NewScript(releaseLeaseLua)
+)
+
+// MemoryOption customizes the in-memory SynchronizationManager.
+type MemoryOption func(*synchronizationManagerMemory)
+
+// defaultJanitorInterval is how often the expired keys of the in-memory SynchronizationManager are deleted.
+const defaultJanitorInterval = time.Minute
+
+// WithMemoryTTL makes the keys of the in-memory SynchronizationManager expire ttl(key) after they are set,
+// so that the keys of the removed jobs do not leak. A key does not expire if ttl returns 0.
+// Expired keys are absent at once, and deleted by a janitor in the background, which runs while there are
+// keys to expire, until the manager is closed or the process shuts down. The manager is an io.Closer.
+// The TTL of the clock of a job must be longer than the interval between its ticks. The clocks of the jobs
+// expire without this option, as the Scheduler sets them with SetGreaterThanTTLCtx.
+func WithMemoryTTL(ttl func(key string) time.Duration) MemoryOption {
+	return func(memory *synchronizationManagerMemory) {
+		memory.ttl = ttl
+	}
+}
+
+// WithMemoryJanitorInterval sets how often the janitor deletes the expired keys. The default is a minute.
+func WithMemoryJanitorInterval(interval time.Duration) MemoryOption {
+	return func(memory *synchronizationManagerMemory) {
+		memory.janitorInterval = interval
+	}
+}
+
 type synchronizationManagerMemory struct {
-	store map[string]int64
+	mu    sync.Mutex
+	store map[string]memoryEntry
+
+	ttl             func(key string) time.Duration
+	janitorInterval time.Duration
+	janitorRunning  bool
+	now             func() time.Time
+	// ctx is canceled by Close.
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	leaseMu sync.Mutex
+	leases  map[string]Lease
+
+	memberMu sync.Mutex
+	members  map[string]map[string]int64
+}
+
+// memoryEntry is a value of the in-memory SynchronizationManager. A zero expiresAt never expires.
+type memoryEntry struct {
+	value     int64
+	expiresAt time.Time
 }
 
-func NewSynchronizationManagerMemory() SynchronizationManager {
+func NewSynchronizationManagerMemory(opts ...MemoryOption) SynchronizationManager {
 	memory := new(synchronizationManagerMemory)
-	memory.store = make(map[string]int64)
+	memory.store = make(map[string]memoryEntry)
+	memory.janitorInterval = defaultJanitorInterval
+	memory.now = time.Now
+	memory.leases = make(map[string]Lease)
+	memory.members = make(map[string]map[string]int64)
+	memory.ctx, memory.cancel = context.WithCancel(shutdownCtx)
+	for _, opt := range opts {
+		opt(memory)
+	}
 	return memory
 }
 
+// Close stops the janitor. The keys still expire, but are only deleted when they are set again.
+func (memory *synchronizationManagerMemory) Close() error {
+	memory.cancel()
+	return nil
+}
+
+// janitor deletes the expired keys until there are no keys to expire, or the manager is closed.
+func (memory *synchronizationManagerMemory) janitor() {
+	ticker := time.NewTicker(memory.janitorInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-memory.ctx.Done():
+			memory.mu.Lock()
+			memory.janitorRunning = false
+			memory.mu.Unlock()
+			return
+		case <-ticker.C:
+			if !memory.deleteExpired() {
+				return
+			}
+		}
+	}
+}
+
+// deleteExpired deletes the expired keys, and stops the janitor if no key is left to expire.
+// It returns whether the janitor keeps running.
+func (memory *synchronizationManagerMemory) deleteExpired() bool {
+	memory.mu.Lock()
+	defer memory.mu.Unlock()
+
+	now := memory.now()
+	expiring := false
+	for key, entry := range memory.store {
+		if entry.expired(now) {
+			delete(memory.store, key)
+		} else if !entry.expiresAt.IsZero() {
+			expiring = true
+		}
+	}
+	memory.janitorRunning = expiring
+	return expiring
+}
+
+func (entry memoryEntry) expired(now time.Time) bool {
+	return !entry.expiresAt.IsZero() && !now.Before(entry.expiresAt)
+}
+
+// set stores value under key with the TTL of the key. The caller holds mu.
+func (memory *synchronizationManagerMemory) set(key string, value int64) {
+	var ttl time.Duration
+	if memory.ttl != nil {
+		ttl = memory.ttl(key)
+	}
+	memory.setTTL(key, value, ttl)
+}
+
+// setTTL stores value under key, which expires after ttl unless it is 0. The caller holds mu.
+func (memory *synchronizationManagerMemory) setTTL(key string, value int64, ttl time.Duration) {
+	entry := memoryEntry{value: value}
+	if ttl > 0 {
+		entry.expiresAt = memory.now().Add(ttl)
+		if !memory.janitorRunning && memory.ctx.Err() == nil {
+			memory.janitorRunning = true
+			go memory.janitor()
+		}
+	}
+	memory.store[key] = entry
+}
+
+// get returns the value of key unless it has expired. The caller holds mu.
+func (memory *synchronizationManagerMemory) get(key string) (int64, bool) {
+	entry, ok := memory.store[key]
+	if !ok || entry.expired(memory.now()) {
+		return 0, false
+	}
+	return entry.value, true
+}
+
 func (memory *synchronizationManagerMemory) Set(key string, value int64) {
-	memory.store[key] = value
+	memory.mu.Lock()
+	defer memory.mu.Unlock()
+	memory.set(key, value)
 }
 
 func (memory *synchronizationManagerMemory) Get(key string) (value int64, ok bool) {
-	value, ok = memory.store[key]
-	return
+	memory.mu.Lock()
+	defer memory.mu.Unlock()
+	return memory.get(key)
 }
 
 func (memory *synchronizationManagerMemory) Delete(key string) {
+	memory.mu.Lock()
+	defer memory.mu.Unlock()
 	delete(memory.store, key)
 }
 
 func (memory *synchronizationManagerMemory) Exists(key string) (ok bool) {
-	_, ok = memory.store[key]
+	memory.mu.Lock()
+	defer memory.mu.Unlock()
+	_, ok = memory.get(key)
 	return
 }
 
 func (memory *synchronizationManagerMemory) SetGreaterThan(key string, value int64) bool {
-	if memory.Exists(key) {
-		oldValue, _ := memory.Get(key)
+	memory.mu.Lock()
+	defer memory.mu.Unlock()
+
+	if oldValue, ok := memory.get(key); ok && value <= oldValue {
+		return false
+	}
+	memory.set(key, value)
+	return true
+}
+
+// SetGreaterThanTTLCtx is like SetGreaterThanCtx, but ttl takes precedence over WithMemoryTTL.
+func (memory *synchronizationManagerMemory) SetGreaterThanTTLCtx(_ context.Context, key string, value int64,
+	ttl time.Duration) (bool, error) {
+	memory.mu.Lock()
+	defer memory.mu.Unlock()
+
+	if oldValue, ok := memory.get(key); ok && value <= oldValue {
+		return false, nil
+	}
+	memory.setTTL(key, value, ttl)
+	return true, nil
+}
+
+func (memory *synchronizationManagerMemory) Prune(_ context.Context, activeJobs []JobInfo) (int, error) {
+	memory.mu.Lock()
+	defer memory.mu.Unlock()
 
-		if value <= oldValue {
-			return false
+	var pruned int
+	for key := range memory.store {
+		if isStaleClock(key, activeJobs) {
+			delete(memory.store, key)
+			pruned++
 		}
 	}
+	return pruned, nil
+}
 
+func (memory *synchronizationManagerMemory) SetCtx(_ context.Context, key string, value int64) error {
 	memory.Set(key, value)
-	return true
+	return nil
+}
+
+func (memory *synchronizationManagerMemory) GetCtx(_ context.Context, key string) (int64, bool, error) {
+	value, ok := memory.Get(key)
+	return value, ok, nil
+}
+
+func (memory *synchronizationManagerMemory) DeleteCtx(_ context.Context, key string) error {
+	memory.Delete(key)
+	return nil
+}
+
+func (memory *synchronizationManagerMemory) ExistsCtx(_ context.Context, key string) (bool, error) {
+	return memory.Exists(key), nil
+}
+
+func (memory *synchronizationManagerMemory) SetGreaterThanCtx(_ context.Context, key string, value int64) (bool, error) {
+	return memory.SetGreaterThan(key, value), nil
+}
+
+func (memory *synchronizationManagerMemory) AcquireLeaseCtx(_ context.Context, key string, lease Lease, now int64) (Lease, bool, error) {
+	memory.leaseMu.Lock()
+	defer memory.leaseMu.Unlock()
+
+	prev, ok := memory.leases[key]
+	if ok && prev.ExpiresAt > now {
+		return prev, false, nil
+	}
+	memory.leases[key] = lease
+	return prev, true, nil
+}
+
+func (memory *synchronizationManagerMemory) RenewLeaseCtx(_ context.Context, key string, lease Lease) (bool, error) {
+	memory.leaseMu.Lock()
+	defer memory.leaseMu.Unlock()
+
+	current, ok := memory.leases[key]
+	if !ok || current.Owner != lease.Owner || current.Tick != lease.Tick {
+		return false, nil
+	}
+	memory.leases[key] = lease
+	return true, nil
+}
+
+func (memory *synchronizationManagerMemory) ReleaseLeaseCtx(_ context.Context, key string, lease Lease) error {
+	memory.leaseMu.Lock()
+	defer memory.leaseMu.Unlock()
+
+	current, ok := memory.leases[key]
+	if ok && current.Owner == lease.Owner && current.Tick == lease.Tick {
+		delete(memory.leases, key)
+	}
+	return nil
+}
+
+func (memory *synchronizationManagerMemory) GetLeaseCtx(_ context.Context, key string) (Lease, bool, error) {
+	memory.leaseMu.Lock()
+	defer memory.leaseMu.Unlock()
+
+	lease, ok := memory.leases[key]
+	return lease, ok, nil
+}
+
+func (memory *synchronizationManagerMemory) HeartbeatCtx(_ context.Context, group, nodeID string, expiresAt int64) error {
+	memory.memberMu.Lock()
+	defer memory.memberMu.Unlock()
+
+	members, ok := memory.members[group]
+	if !ok {
+		members = make(map[string]int64)
+		memory.members[group] = members
+	}
+	members[nodeID] = expiresAt
+	return nil
+}
+
+func (memory *synchronizationManagerMemory) LeaveCtx(_ context.Context, group, nodeID string) error {
+	memory.memberMu.Lock()
+	defer memory.memberMu.Unlock()
+
+	delete(memory.members[group], nodeID)
+	return nil
+}
+
+func (memory *synchronizationManagerMemory) MembersCtx(_ context.Context, group string, now int64) ([]string, error) {
+	memory.memberMu.Lock()
+	defer memory.memberMu.Unlock()
+
+	var live []string
+	for nodeID, expiresAt := range memory.members[group] {
+		if expiresAt > now {
+			live = append(live, nodeID)
+		}
+	}
+	slices.Sort(live)
+	return live, nil
 }
 
 type synchronizationManagerRedis struct {
@@ -69,6 +419,8 @@ type synchronizationManagerRedis struct {
 	store     *redis.Redis
 }
 
+// NewSynchronizationManagerRedis returns a SynchronizationManager which keeps the keys under namespace in redis.
+// In a Redis Cluster, the keys are spread over the masters, unless the namespace has a hash tag, e.g. {jobs}.
 func NewSynchronizationManagerRedis(store *redis.Redis, namespace string) SynchronizationManager {
 	s := new(synchronizationManagerRedis)
 	s.store = store
@@ -81,39 +433,264 @@ func (s *synchronizationManagerRedis) getNamespacedKey(key string) string {
 }
 
 func (s *synchronizationManagerRedis) Set(key string, value int64) {
-	err := s.store.Set(s.getNamespacedKey(key), strconv.FormatInt(value, 10))
-	logx.Must(err)
+	if err := s.SetCtx(context.Background(), key, value); err != nil {
+		logx.Errorf("Can not set synchronization value %q: %v", key, err)
+	}
 }
 
 func (s *synchronizationManagerRedis) Get(key string) (value int64, ok bool) {
-	str, err := s.store.Get(s.getNamespacedKey(key))
-	if err != nil {
-		return 0, false
-	}
-	value, _ = strconv.ParseInt(str, 10, 64)
-	ok = true
+	value, ok, _ = s.GetCtx(context.Background(), key)
 	return
 }
 
 func (s *synchronizationManagerRedis) Delete(key string) {
-	_, _ = s.store.Del(s.getNamespacedKey(key))
+	_ = s.DeleteCtx(context.Background(), key)
 }
 
 func (s *synchronizationManagerRedis) Exists(key string) bool {
-	ok, err := s.store.Exists(s.getNamespacedKey(key))
-	if err != nil {
-		return false
-	}
+	ok, _ := s.ExistsCtx(context.Background(), key)
 	return ok
 }
 
 func (s *synchronizationManagerRedis) SetGreaterThan(key string, value int64) bool {
-	wasSet, err := s.store.ScriptRun(setGreaterThanScript, []string{s.getNamespacedKey(key)}, value)
+	wasSet, _ := s.SetGreaterThanCtx(context.Background(), key, value)
+	return wasSet
+}
+
+func (s *synchronizationManagerRedis) SetCtx(ctx context.Context, key string, value int64) error {
+	return s.store.SetCtx(ctx, s.getNamespacedKey(key), strconv.FormatInt(value, 10))
+}
+
+func (s *synchronizationManagerRedis) GetCtx(ctx context.Context, key string) (int64, bool, error) {
+	str, err := s.store.GetCtx(ctx, s.getNamespacedKey(key))
 	if err != nil {
-		return false
+		return 0, false, err
+	}
+	if str == "" {
+		return 0, false, nil
+	}
+	value, err := strconv.ParseInt(str, 10, 64)
+	if err != nil {
+		return 0, false, err
+	}
+	return value, true, nil
+}
+
+func (s *synchronizationManagerRedis) DeleteCtx(ctx context.Context, key string) error {
+	_, err := s.store.DelCtx(ctx, s.getNamespacedKey(key))
+	return err
+}
+
+func (s *synchronizationManagerRedis) ExistsCtx(ctx context.Context, key string) (bool, error) {
+	return s.store.ExistsCtx(ctx, s.getNamespacedKey(key))
+}
+
+func (s *synchronizationManagerRedis) SetGreaterThanCtx(ctx context.Context, key string, value int64) (bool, error) {
+	return s.setGreaterThan(ctx, []string{s.getNamespacedKey(key)}, value)
+}
+
+// SetGreaterThanTTLCtx is like SetGreaterThanCtx, but the key expires after ttl.
+func (s *synchronizationManagerRedis) SetGreaterThanTTLCtx(ctx context.Context, key string, value int64,
+	ttl time.Duration) (bool, error) {
+	return s.setGreaterThan(ctx, []string{s.getNamespacedKey(key)}, value, ttl.Milliseconds())
+}
+
+func (s *synchronizationManagerRedis) setGreaterThan(ctx context.Context, keys []string, args ...any) (bool, error) {
+	wasSet, err := s.store.ScriptRunCtx(ctx, setGreaterThanScript, keys, args...)
+	if errors.Is(err, redis.Nil) { // The script returns false, which is converted to a nil reply.
+		return false, nil
+	}
+	if err != nil {
+		return false, err
 	}
 	if n, ok := wasSet.(int64); ok && n == 1 {
-		return true
+		return true, nil
+	}
+	return false, nil
+}
+
+// Prune deletes the stale clocks of the namespace, which it finds by scanning the keys. In a Redis Cluster,
+// it scans every master, connecting to them with the addresses, the password and the TLS of the store.
+func (s *synchronizationManagerRedis) Prune(ctx context.Context, activeJobs []JobInfo) (int, error) {
+	keys, err := s.scanClocks(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	var pruned int
+	for _, key := range keys {
+		if !isStaleClock(key, activeJobs) {
+			continue
+		}
+		n, err := s.store.DelCtx(ctx, s.getNamespacedKey(key))
+		if err != nil {
+			return pruned, err
+		}
+		pruned += n
+	}
+	return pruned, nil
+}
+
+// scanCount is how many keys a SCAN of Prune looks at.
+const scanCount = 1000
+
+// scanClocks returns the keys of the clocks in the namespace, without the namespace.
+func (s *synchronizationManagerRedis) scanClocks(ctx context.Context) ([]string, error) {
+	prefix := s.getNamespacedKey("")
+	match := escapeGlob(prefix) + "clock:*"
+	if s.store.Type != redis.ClusterType {
+		return scanKeys(prefix, func(cursor uint64) ([]string, uint64, error) {
+			return s.store.ScanCtx(ctx, cursor, match, scanCount)
+		})
+	}
+
+	// The store sends every SCAN to a random node, so the masters are scanned one by one.
+	client := red.NewClusterClient(clusterOptions(s.store))
+	defer client.Close()
+	var mu sync.Mutex
+	var keys []string
+	err := client.ForEachMaster(ctx, func(ctx context.Context, master *red.Client) error {
+		scanned, err := scanKeys(prefix, func(cursor uint64) ([]string, uint64, error) {
+			return master.Scan(ctx, cursor, match, scanCount).Result()
+		})
+		mu.Lock()
+		defer mu.Unlock()
+		keys = append(keys, scanned...)
+		return err
+	})
+	return keys, err
+}
+
+// clusterOptions returns the options of a client connecting to the Redis Cluster of store,
+// like the one go-zero keeps for it, but without its hooks.
+func clusterOptions(store *redis.Redis) *red.ClusterOptions {
+	options := &red.ClusterOptions{
+		Addrs:      strings.Split(store.Addr, ","),
+		Password:   store.Pass,
+		MaxRetries: 3,
+	}
+	// go-zero does not export whether the store uses TLS, nor the client it has made of it.
+	if reflect.ValueOf(store).Elem().FieldByName("tls").Bool() {
+		options.TLSConfig = &tls.Config{InsecureSkipVerify: true} //nolint:gosec // The same as go-zero.
+	}
+	return options
+}
+
+// scanKeys returns the keys of all iterations of scan, without prefix.
+func scanKeys(prefix string, scan func(cursor uint64) ([]string, uint64, error)) ([]string, error) {
+	var keys []string
+	var cursor uint64
+	for {
+		scanned, next, err := scan(cursor)
+		if err != nil {
+			return keys, err
+		}
+		for _, key := range scanned {
+			keys = append(keys, strings.TrimPrefix(key, prefix))
+		}
+		if next == 0 {
+			return keys, nil
+		}
+		cursor = next
+	}
+}
+
+// globEscaper escapes the special characters of the glob-style patterns of redis.
+var globEscaper = strings.NewReplacer(`\`, `\\`, "*", `\*`, "?", `\?`, "[", `\[`, "]", `\]`)
+
+func escapeGlob(s string) string {
+	return globEscaper.Replace(s)
+}
+
+func (s *synchronizationManagerRedis) AcquireLeaseCtx(ctx context.Context, key string, lease Lease, now int64) (Lease, bool, error) {
+	result, err := s.store.ScriptRunCtx(ctx, acquireLeaseScript, []string{s.getNamespacedKey(key)},
+		lease.Owner, lease.Tick, lease.ExpiresAt, now)
+	if err != nil {
+		return Lease{}, false, err
+	}
+	values, ok := result.([]any)
+	if !ok || len(values) != 4 {
+		return Lease{}, false, fmt.Errorf("unexpected result of acquire_lease.lua: %v", result)
+	}
+	acquired, _ := values[0].(int64)
+	owner, _ := values[1].(string)
+	tick, _ := values[2].(string)
+	expiresAt, _ := values[3].(string)
+	prev, err := parseLease(owner, tick, expiresAt)
+	return prev, acquired == 1, err
+}
+
+func (s *synchronizationManagerRedis) RenewLeaseCtx(ctx context.Context, key string, lease Lease) (bool, error) {
+	renewed, err := s.store.ScriptRunCtx(ctx, renewLeaseScript, []string{s.getNamespacedKey(key)},
+		lease.Owner, lease.Tick, lease.ExpiresAt)
+	if err != nil {
+		return false, err
+	}
+	n, _ := renewed.(int64)
+	return n == 1, nil
+}
+
+func (s *synchronizationManagerRedis) ReleaseLeaseCtx(ctx context.Context, key string, lease Lease) error {
+	_, err := s.store.ScriptRunCtx(ctx, releaseLeaseScript, []string{s.getNamespacedKey(key)},
+		lease.Owner, lease.Tick)
+	return err
+}
+
+func (s *synchronizationManagerRedis) GetLeaseCtx(ctx context.Context, key string) (Lease, bool, error) {
+	fields, err := s.store.HgetallCtx(ctx, s.getNamespacedKey(key))
+	if err != nil || len(fields) == 0 {
+		return Lease{}, false, err
+	}
+	lease, err := parseLease(fields["owner"], fields["tick"], fields["expires_at"])
+	if err != nil {
+		return Lease{}, false, err
+	}
+	return lease, true, nil
+}
+
+func (s *synchronizationManagerRedis) getMembersKey(group string) string {
+	return s.getNamespacedKey("members:" + group)
+}
+
+func (s *synchronizationManagerRedis) HeartbeatCtx(ctx context.Context, group, nodeID string, expiresAt int64) error {
+	_, err := s.store.ZaddCtx(ctx, s.getMembersKey(group), expiresAt, nodeID)
+	return err
+}
+
+func (s *synchronizationManagerRedis) LeaveCtx(ctx context.Context, group, nodeID string) error {
+	_, err := s.store.ZremCtx(ctx, s.getMembersKey(group), nodeID)
+	return err
+}
+
+func (s *synchronizationManagerRedis) MembersCtx(ctx context.Context, group string, now int64) ([]string, error) {
+	key := s.getMembersKey(group)
+	// Drop the expired members, so that the sorted set does not grow with every restart of the nodes.
+	if _, err := s.store.ZremrangebyscoreCtx(ctx, key, 0, now); err != nil {
+		return nil, err
+	}
+	pairs, err := s.store.ZrangebyscoreWithScoresCtx(ctx, key, now+1, math.MaxInt64)
+	if err != nil {
+		return nil, err
+	}
+	members := make([]string, 0, len(pairs))
+	for _, pair := range pairs {
+		members = append(members, pair.Key)
+	}
+	slices.Sort(members)
+	return members, nil
+}
+
+func parseLease(owner, tick, expiresAt string) (lease Lease, err error) {
+	lease.Owner = owner
+	if tick != "" {
+		if lease.Tick, err = strconv.ParseInt(tick, 10, 64); err != nil {
+			return Lease{}, err
+		}
+	}
+	if expiresAt != "" {
+		if lease.ExpiresAt, err = strconv.ParseInt(expiresAt, 10, 64); err != nil {
+			return Lease{}, err
+		}
 	}
-	return false
+	return lease, nil
 }
@@ -1,49 +1,214 @@
 package goschedule
 
 import (
+	"context"
+	"io"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/zeromicro/go-zero/core/stores/redis"
 )
 
-func TestSynchronizationManagerMemory(t *testing.T) {
-	memory := NewSynchronizationManagerMemory()
+// testSynchronizationManager is the contract every SynchronizationManager must fulfill.
+func testSynchronizationManager(t *testing.T, syncMgr SynchronizationManager) {
+	syncMgr.Delete("key")
 
-	ok := memory.SetGreaterThan("key", 12345)
+	ok := syncMgr.SetGreaterThan("key", 12345)
 	if !ok {
 		t.Fail()
 	}
 
-	ok = memory.SetGreaterThan("key", 12345)
+	ok = syncMgr.SetGreaterThan("key", 12345)
 	if ok {
 		t.Fail()
 	}
 
-	ok = memory.SetGreaterThan("key", 12346)
+	ok = syncMgr.SetGreaterThan("key", 12346)
 	if !ok {
 		t.Fail()
 	}
-}
 
-func TestSynchronizationManagerRedis(t *testing.T) {
-	redis := NewSynchronizationManagerRedis(
-		redis.MustNewRedis(redis.RedisConf{Host: "127.0.0.1:6379", Type: "node"}),
-		"TestSynchronizationManagerRedis",
-	)
-	redis.Delete("key")
+	ok = syncMgr.SetGreaterThan("key", 12340)
+	if ok {
+		t.Fail()
+	}
 
-	ok := redis.SetGreaterThan("key", 12345)
-	if !ok {
+	value, ok := syncMgr.Get("key")
+	if !ok || value != 12346 {
 		t.Fail()
 	}
 
-	ok = redis.SetGreaterThan("key", 12345)
-	if ok {
+	syncMgr.Set("key", 100)
+	value, ok = syncMgr.Get("key")
+	if !ok || value != 100 {
 		t.Fail()
 	}
 
-	ok = redis.SetGreaterThan("key", 12346)
-	if !ok {
+	syncMgr.Delete("key")
+	if syncMgr.Exists("key") {
+		t.Fail()
+	}
+	if _, ok = syncMgr.Get("key"); ok {
 		t.Fail()
 	}
 }
+
+// testContextSynchronizationManager is the contract every ContextSynchronizationManager must fulfill.
+func testContextSynchronizationManager(t *testing.T, syncMgr ContextSynchronizationManager) {
+	ctx := context.Background()
+	assert.NoError(t, syncMgr.DeleteCtx(ctx, "ctx-key"))
+
+	ok, err := syncMgr.SetGreaterThanCtx(ctx, "ctx-key", 12345)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = syncMgr.SetGreaterThanCtx(ctx, "ctx-key", 12345)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	assert.NoError(t, syncMgr.SetCtx(ctx, "ctx-key", 100))
+	value, ok, err := syncMgr.GetCtx(ctx, "ctx-key")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.EqualValues(t, 100, value)
+
+	assert.NoError(t, syncMgr.DeleteCtx(ctx, "ctx-key"))
+	ok, err = syncMgr.ExistsCtx(ctx, "ctx-key")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+	_, ok, err = syncMgr.GetCtx(ctx, "ctx-key")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestSynchronizationManagerMemory(t *testing.T) {
+	syncMgr := NewSynchronizationManagerMemory()
+	testSynchronizationManager(t, syncMgr)
+	testContextSynchronizationManager(t, syncMgr.(ContextSynchronizationManager))
+}
+
+func TestSynchronizationManagerMemoryConcurrent(t *testing.T) {
+	const ticks, nodes = 200, 20
+	syncMgr := NewSynchronizationManagerMemory()
+
+	// Many nodes race for every tick, and exactly one of them wins it.
+	var wg sync.WaitGroup
+	var wins [ticks + 1]atomic.Int32
+	for node := 0; node < nodes; node++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for tick := 1; tick <= ticks; tick++ {
+				if syncMgr.SetGreaterThan("clock", int64(tick)) {
+					wins[tick].Add(1)
+				}
+				syncMgr.Exists("clock")
+				syncMgr.Set("other", int64(tick))
+			}
+		}()
+	}
+	wg.Wait()
+
+	total := 0
+	for tick := 1; tick <= ticks; tick++ {
+		assert.LessOrEqual(t, wins[tick].Load(), int32(1), "tick %d", tick)
+		total += int(wins[tick].Load())
+	}
+	assert.Positive(t, total)
+	value, ok := syncMgr.Get("clock")
+	assert.True(t, ok)
+	assert.EqualValues(t, ticks, value)
+}
+
+func TestSynchronizationManagerMemoryTTL(t *testing.T) {
+	syncMgr := NewSynchronizationManagerMemory(WithMemoryTTL(func(key string) time.Duration {
+		if strings.HasPrefix(key, "paused:") {
+			return 0
+		}
+		return time.Minute
+	}), WithMemoryJanitorInterval(time.Millisecond))
+	memory := syncMgr.(*synchronizationManagerMemory)
+	now := time.Now()
+	var mu sync.Mutex
+	memory.mu.Lock()
+	memory.now = func() time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		return now
+	}
+	memory.mu.Unlock()
+	advance := func(d time.Duration) {
+		mu.Lock()
+		defer mu.Unlock()
+		now = now.Add(d)
+	}
+
+	syncMgr.Set("clock:job", 1)
+	syncMgr.Set("paused:job", 1)
+	advance(30 * time.Second)
+	assert.True(t, syncMgr.SetGreaterThan("clock:job", 2))
+
+	// Setting a key renews its TTL.
+	advance(45 * time.Second)
+	value, ok := syncMgr.Get("clock:job")
+	assert.True(t, ok)
+	assert.EqualValues(t, 2, value)
+
+	advance(15 * time.Second)
+	assert.False(t, syncMgr.Exists("clock:job"))
+	assert.True(t, syncMgr.SetGreaterThan("clock:job", 1), "an expired key is absent")
+	assert.True(t, syncMgr.Exists("paused:job"))
+
+	// The janitor deletes the expired keys.
+	advance(time.Minute)
+	assert.Eventually(t, func() bool {
+		memory.mu.Lock()
+		defer memory.mu.Unlock()
+		_, ok := memory.store["clock:job"]
+		return !ok
+	}, time.Second, time.Millisecond)
+	assert.True(t, syncMgr.Exists("paused:job"))
+
+	// The janitor stops once no key is left to expire, and starts again with the next one.
+	janitorRunning := func() bool {
+		memory.mu.Lock()
+		defer memory.mu.Unlock()
+		return memory.janitorRunning
+	}
+	assert.Eventually(t, func() bool { return !janitorRunning() }, time.Second, time.Millisecond)
+	syncMgr.Set("clock:job", 1)
+	assert.True(t, janitorRunning())
+
+	// Closing the manager stops the janitor for good.
+	assert.NoError(t, syncMgr.(io.Closer).Close())
+	assert.Eventually(t, func() bool { return !janitorRunning() }, time.Second, time.Millisecond)
+	syncMgr.Set("clock:other", 1)
+	assert.False(t, janitorRunning())
+}
+
+func TestSynchronizationManagerRedis(t *testing.T) {
+	syncMgr := NewSynchronizationManagerRedis(
+		redis.MustNewRedis(redis.RedisConf{Host: "127.0.0.1:6379", Type: "node"}),
+		"TestSynchronizationManagerRedis",
+	)
+	testSynchronizationManager(t, syncMgr)
+	testContextSynchronizationManager(t, syncMgr.(ContextSynchronizationManager))
+}
+
+func TestSynchronizationManagerRedisDown(t *testing.T) {
+	syncMgr := NewSynchronizationManagerRedis(
+		redis.New("127.0.0.1:1", redis.WithPass("")),
+		"TestSynchronizationManagerRedisDown",
+	).(ContextSynchronizationManager)
+
+	_, err := syncMgr.SetGreaterThanCtx(context.Background(), "key", 12345)
+	assert.Error(t, err)
+}
+
+func TestSynchronizationManagerAdapter(t *testing.T) {
+	testContextSynchronizationManager(t, synchronizationManagerAdapter{NewSynchronizationManagerMemory()})
+}
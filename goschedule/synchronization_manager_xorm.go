@@ -0,0 +1,217 @@
+package goschedule
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/zeromicro/go-zero/core/logx"
+	"xorm.io/xorm"
+	"xorm.io/xorm/schemas"
+)
+
+var _ SynchronizationManager = (*synchronizationManagerXorm)(nil)
+var _ ContextSynchronizationManager = (*synchronizationManagerXorm)(nil)
+var _ MembershipManager = (*synchronizationManagerXorm)(nil)
+
+// synchronizationValue is a row of the table used by synchronizationManagerXorm.
+type synchronizationValue struct {
+	Key   string `xorm:"pk varchar(255) 'key'"`
+	Value int64  `xorm:"notnull 'value'"`
+}
+
+type synchronizationManagerXorm struct {
+	engine *xorm.Engine
+	table  string
+
+	// Raw SQL statements which can not be expressed with the xorm builder.
+	setSQL            string
+	setGreaterThanSQL string
+}
+
+// NewSynchronizationManagerXorm returns a SynchronizationManager which stores values in a SQL table.
+// The table is created if it does not exist.
+//
+// SetGreaterThan is a single conditional UPSERT on MySQL, PostgreSQL and SQLite.
+// For MySQL, the driver must not be configured with clientFoundRows=true,
+// otherwise unchanged rows are reported as affected.
+func NewSynchronizationManagerXorm(engine *xorm.Engine, table string) SynchronizationManager {
+	s := new(synchronizationManagerXorm)
+	s.engine = engine
+	s.table = table
+
+	err := engine.Table(table).Sync(new(synchronizationValue))
+	logx.Must(err)
+
+	t, k, v := engine.Quote(table), engine.Quote("key"), engine.Quote("value")
+	switch engine.Dialect().URI().DBType {
+	case schemas.MYSQL:
+		s.setSQL = fmt.Sprintf("INSERT INTO %s (%s, %s) VALUES (?, ?) ON DUPLICATE KEY UPDATE %s = VALUES(%s)",
+			t, k, v, v, v)
+		s.setGreaterThanSQL = fmt.Sprintf("INSERT INTO %s (%s, %s) VALUES (?, ?) "+
+			"ON DUPLICATE KEY UPDATE %s = IF(%s < VALUES(%s), VALUES(%s), %s)",
+			t, k, v, v, v, v, v, v)
+	case schemas.POSTGRES, schemas.SQLITE:
+		s.setSQL = fmt.Sprintf("INSERT INTO %s (%s, %s) VALUES (?, ?) ON CONFLICT (%s) DO UPDATE SET %s = excluded.%s",
+			t, k, v, k, v, v)
+		s.setGreaterThanSQL = fmt.Sprintf("INSERT INTO %s (%s, %s) VALUES (?, ?) "+
+			"ON CONFLICT (%s) DO UPDATE SET %s = excluded.%s WHERE %s.%s < excluded.%s",
+			t, k, v, k, v, v, t, v, v)
+	}
+
+	return s
+}
+
+func (s *synchronizationManagerXorm) Set(key string, value int64) {
+	if err := s.SetCtx(context.Background(), key, value); err != nil {
+		logx.Errorf("Can not set synchronization value %q: %v", key, err)
+	}
+}
+
+func (s *synchronizationManagerXorm) Get(key string) (value int64, ok bool) {
+	value, ok, err := s.GetCtx(context.Background(), key)
+	if err != nil {
+		logx.Errorf("Can not get synchronization value %q: %v", key, err)
+	}
+	return
+}
+
+func (s *synchronizationManagerXorm) Delete(key string) {
+	if err := s.DeleteCtx(context.Background(), key); err != nil {
+		logx.Errorf("Can not delete synchronization value %q: %v", key, err)
+	}
+}
+
+func (s *synchronizationManagerXorm) Exists(key string) bool {
+	ok, err := s.ExistsCtx(context.Background(), key)
+	if err != nil {
+		logx.Errorf("Can not check synchronization value %q: %v", key, err)
+	}
+	return ok
+}
+
+func (s *synchronizationManagerXorm) SetGreaterThan(key string, value int64) bool {
+	wasSet, err := s.SetGreaterThanCtx(context.Background(), key, value)
+	if err != nil {
+		logx.Errorf("Can not set synchronization value %q: %v", key, err)
+	}
+	return wasSet
+}
+
+func (s *synchronizationManagerXorm) SetCtx(ctx context.Context, key string, value int64) error {
+	if s.setSQL == "" {
+		return s.update(ctx, key, value)
+	}
+	_, err := s.engine.Context(ctx).Exec(s.setSQL, key, value)
+	return err
+}
+
+func (s *synchronizationManagerXorm) GetCtx(ctx context.Context, key string) (int64, bool, error) {
+	var row synchronizationValue
+	ok, err := s.engine.Context(ctx).Table(s.table).ID(key).Get(&row)
+	if err != nil {
+		return 0, false, err
+	}
+	return row.Value, ok, nil
+}
+
+func (s *synchronizationManagerXorm) DeleteCtx(ctx context.Context, key string) error {
+	_, err := s.engine.Context(ctx).Table(s.table).ID(key).Delete(new(synchronizationValue))
+	return err
+}
+
+func (s *synchronizationManagerXorm) ExistsCtx(ctx context.Context, key string) (bool, error) {
+	return s.engine.Context(ctx).Table(s.table).ID(key).Exist(new(synchronizationValue))
+}
+
+func (s *synchronizationManagerXorm) SetGreaterThanCtx(ctx context.Context, key string, value int64) (bool, error) {
+	if s.setGreaterThanSQL == "" {
+		return s.updateGreaterOrInsert(ctx, key, value)
+	}
+
+	result, err := s.engine.Context(ctx).Exec(s.setGreaterThanSQL, key, value)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// update is the fallback of Set for databases without an UPSERT statement.
+func (s *synchronizationManagerXorm) update(ctx context.Context, key string, value int64) error {
+	n, err := s.engine.Context(ctx).Table(s.table).ID(key).Cols("value").Update(&synchronizationValue{Value: value})
+	if err != nil || n > 0 {
+		return err
+	}
+	_, err = s.engine.Context(ctx).Table(s.table).Insert(&synchronizationValue{Key: key, Value: value})
+	return err
+}
+
+// updateGreaterOrInsert is the fallback of SetGreaterThan for databases without an UPSERT statement.
+// Both the conditional update and the insert are atomic, and a failed insert means
+// another node has inserted the key in the meantime.
+func (s *synchronizationManagerXorm) updateGreaterOrInsert(ctx context.Context, key string, value int64) (bool, error) {
+	n, err := s.engine.Context(ctx).Table(s.table).ID(key).Where(s.engine.Quote("value")+" < ?", value).
+		Cols("value").Update(&synchronizationValue{Value: value})
+	if err != nil {
+		return false, err
+	}
+	if n > 0 {
+		return true, nil
+	}
+	exists, err := s.ExistsCtx(ctx, key)
+	if err != nil || exists {
+		return false, err
+	}
+	_, err = s.engine.Context(ctx).Table(s.table).Insert(&synchronizationValue{Key: key, Value: value})
+	return err == nil, nil
+}
+
+// The members are stored as rows whose values are their expiry times.
+func getMemberKeyPrefix(group string) string {
+	return "member:" + group + ":"
+}
+
+func (s *synchronizationManagerXorm) HeartbeatCtx(ctx context.Context, group, nodeID string, expiresAt int64) error {
+	return s.SetCtx(ctx, getMemberKeyPrefix(group)+nodeID, expiresAt)
+}
+
+func (s *synchronizationManagerXorm) LeaveCtx(ctx context.Context, group, nodeID string) error {
+	return s.DeleteCtx(ctx, getMemberKeyPrefix(group)+nodeID)
+}
+
+func (s *synchronizationManagerXorm) MembersCtx(ctx context.Context, group string, now int64) ([]string, error) {
+	prefix := getMemberKeyPrefix(group)
+	pattern := strings.NewReplacer("!", "!!", "%", "!%", "_", "!_").Replace(prefix) + "%"
+	keyLike := s.engine.Quote("key") + " LIKE ? ESCAPE '!'"
+	// Drop the expired members, so that the table does not grow with every restart of the nodes.
+	// LIKE may match the expired members of another group as well, which is harmless.
+	_, err := s.engine.Context(ctx).Table(s.table).
+		Where(keyLike, pattern).
+		And(s.engine.Quote("value")+" <= ?", now).
+		Delete(new(synchronizationValue))
+	if err != nil {
+		return nil, err
+	}
+	var rows []synchronizationValue
+	err = s.engine.Context(ctx).Table(s.table).
+		Where(keyLike, pattern).
+		And(s.engine.Quote("value")+" > ?", now).
+		Find(&rows)
+	if err != nil {
+		return nil, err
+	}
+	members := make([]string, 0, len(rows))
+	for _, row := range rows {
+		// LIKE may be case insensitive.
+		if nodeID, ok := strings.CutPrefix(row.Key, prefix); ok {
+			members = append(members, nodeID)
+		}
+	}
+	slices.Sort(members)
+	return members, nil
+}
@@ -0,0 +1,61 @@
+package goschedule
+
+import (
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	_ "modernc.org/sqlite"
+	"xorm.io/xorm"
+)
+
+func newTestEngine(t *testing.T) *xorm.Engine {
+	dsn := filepath.Join(t.TempDir(), "test.db") + "?_pragma=busy_timeout(5000)"
+	engine, err := xorm.NewEngine("sqlite", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = engine.Close() })
+	return engine
+}
+
+func TestSynchronizationManagerXorm(t *testing.T) {
+	syncMgr := NewSynchronizationManagerXorm(newTestEngine(t), "synchronization")
+	testSynchronizationManager(t, syncMgr)
+	testContextSynchronizationManager(t, syncMgr.(ContextSynchronizationManager))
+}
+
+func TestSynchronizationManagerXormConcurrent(t *testing.T) {
+	engine := newTestEngine(t)
+	syncMgr := NewSynchronizationManagerXorm(engine, "synchronization")
+	// Another manager on the same table, as if it were on another node.
+	otherSyncMgr := NewSynchronizationManagerXorm(engine, "synchronization")
+
+	for value := int64(1); value <= 10; value++ {
+		var wins atomic.Int32
+		var wg sync.WaitGroup
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func(syncMgr SynchronizationManager) {
+				defer wg.Done()
+				if syncMgr.SetGreaterThan("key", value) {
+					wins.Add(1)
+				}
+			}([]SynchronizationManager{syncMgr, otherSyncMgr}[i%2])
+		}
+		wg.Wait()
+		assert.EqualValues(t, 1, wins.Load())
+	}
+}
+
+func TestSynchronizationManagerXormFallback(t *testing.T) {
+	syncMgr := NewSynchronizationManagerXorm(newTestEngine(t), "synchronization")
+	// Pretend the database does not support UPSERT.
+	syncMgr.(*synchronizationManagerXorm).setSQL = ""
+	syncMgr.(*synchronizationManagerXorm).setGreaterThanSQL = ""
+
+	testSynchronizationManager(t, syncMgr)
+	testContextSynchronizationManager(t, syncMgr.(ContextSynchronizationManager))
+}
@@ -1,13 +1,22 @@
 package goschedule
 
-import "time"
+import (
+	"context"
+	"time"
+
+	"github.com/zeromicro/go-zero/core/logx"
+)
 
 type SynchronizedClock struct {
 	key                    string
-	synchronizationManager SynchronizationManager
+	synchronizationManager ContextSynchronizationManager
 }
 
 func NewSynchronizedClock(id string, syncMgr SynchronizationManager) *SynchronizedClock {
+	return newSynchronizedClock(id, toContextSynchronizationManager(syncMgr))
+}
+
+func newSynchronizedClock(id string, syncMgr ContextSynchronizationManager) *SynchronizedClock {
 	clock := new(SynchronizedClock)
 	clock.key = "clock:" + id
 	clock.synchronizationManager = syncMgr
@@ -15,9 +24,39 @@ func NewSynchronizedClock(id string, syncMgr SynchronizationManager) *Synchroniz
 }
 
 func (clock *SynchronizedClock) Set(timestamp time.Time) bool {
-	return clock.synchronizationManager.SetGreaterThan(clock.key, timestamp.UnixMilli())
+	wasSet, err := clock.SetCtx(context.Background(), timestamp)
+	if err != nil {
+		logx.Errorf("Can not set synchronized clock %q: %v", clock.key, err)
+	}
+	return wasSet
+}
+
+// SetCtx moves the clock forward to timestamp. It reports false if the clock
+// is already at or after timestamp, which means another node has won.
+func (clock *SynchronizedClock) SetCtx(ctx context.Context, timestamp time.Time) (bool, error) {
+	return clock.synchronizationManager.SetGreaterThanCtx(ctx, clock.key, timestamp.UnixMilli())
+}
+
+// setTTLCtx is like SetCtx, but the clock expires after ttl if the SynchronizationManager supports it.
+func (clock *SynchronizedClock) setTTLCtx(ctx context.Context, timestamp time.Time, ttl time.Duration) (bool, error) {
+	return setGreaterThan(ctx, clock.synchronizationManager, clock.key, timestamp.UnixMilli(), ttl)
+}
+
+// GetCtx returns the time the clock is at.
+func (clock *SynchronizedClock) GetCtx(ctx context.Context) (time.Time, bool, error) {
+	value, ok, err := clock.synchronizationManager.GetCtx(ctx, clock.key)
+	if err != nil || !ok {
+		return time.Time{}, false, err
+	}
+	return time.UnixMilli(value), true, nil
 }
 
 func (clock *SynchronizedClock) Reset() {
-	clock.synchronizationManager.Delete(clock.key)
+	if err := clock.ResetCtx(context.Background()); err != nil {
+		logx.Errorf("Can not reset synchronized clock %q: %v", clock.key, err)
+	}
+}
+
+func (clock *SynchronizedClock) ResetCtx(ctx context.Context) error {
+	return clock.synchronizationManager.DeleteCtx(ctx, clock.key)
 }
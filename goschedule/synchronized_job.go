@@ -0,0 +1,524 @@
+package goschedule
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-co-op/gocron/v2"
+	"github.com/robfig/cron/v3"
+	"github.com/zeromicro/go-zero/core/logx"
+	"github.com/zeromicro/go-zero/core/trace"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	oteltrace "go.opentelemetry.io/otel/trace"
+)
+
+// synchronizedJob is a job scheduled by a Scheduler.
+type synchronizedJob struct {
+	id        string
+	rule      string
+	cb        func(ctx context.Context) error
+	options   jobOptions
+	parsed    jobRule
+	schedule  cron.Schedule
+	clock     *SynchronizedClock
+	scheduler *Scheduler
+	job       gocron.Job
+	ctx       context.Context
+	cancel    context.CancelFunc
+
+	// membershipManager is only set for sharded jobs.
+	membershipManager MembershipManager
+
+	// leaseManager is only set in the lease mode.
+	leaseManager LeaseManager
+	leaseKey     string
+
+	// next is the scheduled time of the next tick, as known after the latest one.
+	mu   sync.Mutex
+	next time.Time
+
+	// ready is closed once the job has been registered, as gocron may run it right away.
+	ready chan struct{}
+	// caughtUp is closed once the missed ticks have been caught up. The ticks of gocron wait for it,
+	// or they would win the clock past the missed ticks.
+	caughtUp chan struct{}
+
+	// misfired is true for a one-shot job scheduled after its time.
+	misfired bool
+
+	clockFailures atomic.Uint64
+}
+
+// start runs the background work of the job once the Scheduler has started.
+func (j *synchronizedJob) start() {
+	if j.leaseManager != nil && j.options.leaseExpiredPolicy != LeaseExpiredSkip {
+		go j.watchLease()
+	}
+	if j.membershipManager != nil {
+		go j.heartbeat()
+	}
+	defer close(j.caughtUp)
+	j.catchUp()
+}
+
+// task is called by gocron on every tick.
+func (j *synchronizedJob) task() {
+	<-j.ready
+	select {
+	case <-j.caughtUp:
+	case <-j.ctx.Done():
+		return
+	}
+
+	nextTimestamp, err := j.nextRun()
+	if err != nil {
+		logx.Errorf("Can not schedule synchronized job %q: job.NextRun: %v", j.id, err)
+		return
+	}
+	scheduled := j.parsed.tick(j.advance(nextTimestamp), j.scheduler.clock.Now())
+	if j.misfired && j.options.misfirePolicy == MisfireSkip {
+		j.recordSkip(scheduled)
+		return
+	}
+	if j.excluded(scheduled) {
+		j.recordSkip(scheduled)
+		return
+	}
+	if j.membershipManager != nil {
+		if j.paused() {
+			j.skip()
+		} else {
+			j.runShards(scheduled)
+		}
+		return
+	}
+
+	// A busy node does not win the tick, so that another node can run it.
+	if !j.scheduler.tryAcquireSlot() {
+		logx.Infof("Node %q has reached its concurrency limit, leave synchronized job %q to the other nodes",
+			j.scheduler.nodeID, j.id)
+		j.skip()
+		j.recordUnclaimedSkip(scheduled)
+		return
+	}
+	slot := &tickSlot{scheduler: j.scheduler, held: true}
+	defer slot.release()
+
+	wasSet := j.setClock(j.parsed.clockAfter(scheduled))
+
+	switch {
+	case !wasSet:
+		j.skip()
+	case j.paused():
+		j.recordSkip(scheduled)
+		j.skip()
+	default:
+		j.runTick(scheduled, slot)
+	}
+}
+
+// skip reports a tick which did not run on this node.
+func (j *synchronizedJob) skip() {
+	now := j.scheduler.clock.Now()
+	j.record(RunRecord{Start: now, End: now, Skipped: true})
+	metricRunsSkipped.Inc(j.id)
+	if j.scheduler.hooks.OnSkipped != nil {
+		j.scheduler.hooks.OnSkipped(j.id)
+	}
+}
+
+// nextRun returns the time of the next tick after now. Unlike job.NextRun, it never returns
+// the current tick, which gocron still keeps if its timer has fired at exactly the time of the tick.
+func (j *synchronizedJob) nextRun() (time.Time, error) {
+	runs, err := j.job.NextRuns(2)
+	if err != nil {
+		return time.Time{}, err
+	}
+	now := j.scheduler.clock.Now()
+	for _, run := range runs {
+		if run.After(now) {
+			return run, nil
+		}
+	}
+	return time.Time{}, nil
+}
+
+// nextScheduled returns the scheduled time of the next tick which is not excluded by the calendar of the job,
+// or zero if there is none. It is the next tick of gocron, unless that one is excluded.
+func (j *synchronizedJob) nextScheduled() time.Time {
+	next, _ := j.nextRun()
+	if next.IsZero() || j.options.calendar == nil || j.parsed.kind == ruleStartup {
+		return next
+	}
+	return j.schedule.Next(next.Add(-time.Nanosecond))
+}
+
+// advance records the scheduled time of the next tick, and returns the scheduled time of the current one.
+func (j *synchronizedJob) advance(next time.Time) time.Time {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	scheduled := j.next
+	j.next = next
+	if scheduled.IsZero() || scheduled.After(j.scheduler.clock.Now()) {
+		scheduled = j.scheduler.clock.Now().Truncate(time.Second)
+	}
+	return scheduled
+}
+
+// setClock tries to win the tick, following the ClockFailurePolicy of the Scheduler on errors.
+func (j *synchronizedJob) setClock(timestamp time.Time) bool {
+	return j.setClockOf(j.clock, timestamp)
+}
+
+// setClockOf is like setClock, but sets the given clock.
+func (j *synchronizedJob) setClockOf(clock *SynchronizedClock, timestamp time.Time) bool {
+	ttl := j.clockTTL(timestamp)
+	wasSet, err := clock.setTTLCtx(j.ctx, timestamp, ttl)
+	if err == nil {
+		return wasSet
+	}
+
+	s := j.scheduler
+	if s.clockFailurePolicy == ClockFailureRetry {
+		backoff := s.clockRetryBackoff
+		for attempt := 1; attempt < s.clockRetryAttempts; attempt++ {
+			select {
+			case <-j.ctx.Done():
+				return false
+			case <-j.scheduler.clock.After(backoff):
+			}
+			backoff *= 2
+
+			wasSet, err = clock.setTTLCtx(j.ctx, timestamp, ttl)
+			if err == nil {
+				return wasSet
+			}
+		}
+	}
+
+	j.clockFailures.Add(1)
+	if s.clockFailurePolicy == ClockFailureRunAnyway {
+		logx.Errorf("Can not set synchronized clock of job %q, run it anyway: %v", j.id, err)
+		return true
+	}
+	logx.Errorf("Can not set synchronized clock of job %q, skip it: %v", j.id, err)
+	return false
+}
+
+// runTick runs a tick which this node has won with slot, holding a lease for it in the lease mode.
+func (j *synchronizedJob) runTick(scheduled time.Time, slot *tickSlot) {
+	if len(j.options.upstreamIDs) > 0 {
+		if err := j.awaitDependencies(scheduled, slot); err != nil {
+			if j.ctx.Err() == nil {
+				now := j.scheduler.clock.Now()
+				j.finish(withScheduledTime(j.ctx, scheduled), RunRecord{Start: now, End: now}, err)
+				j.recordCompletion(scheduled, err)
+			}
+			return
+		}
+	}
+	if j.leaseManager == nil {
+		j.run(j.ctx, scheduled)
+		return
+	}
+
+	now := j.scheduler.clock.Now()
+	lease := Lease{
+		Owner:     j.scheduler.nodeID,
+		Tick:      scheduled.UnixMilli(),
+		ExpiresAt: now.Add(j.options.leaseTTL).UnixMilli(),
+	}
+	prev, acquired, err := j.leaseManager.AcquireLeaseCtx(j.ctx, j.leaseKey, lease, now.UnixMilli())
+	if err != nil {
+		logx.Errorf("Can not acquire the lease of synchronized job %q, run it without lease: %v", j.id, err)
+		j.run(j.ctx, scheduled)
+		return
+	}
+	if !acquired {
+		switch j.options.overlapPolicy {
+		case OverlapSkip:
+			logx.Infof("The previous run of synchronized job %q on node %q is still in progress, skip it", j.id, prev.Owner)
+			j.skip()
+			j.recordSkip(scheduled)
+		case OverlapQueue:
+			queued, ok := j.enqueue(now)
+			if !ok {
+				logx.Infof("The previous run of synchronized job %q on node %q is still in progress, "+
+					"and another tick is queued, skip it", j.id, prev.Owner)
+				j.skip()
+				j.recordSkip(scheduled)
+				return
+			}
+			logx.Infof("The previous run of synchronized job %q on node %q is still in progress, queue it", j.id, prev.Owner)
+			if lease, acquired = j.queueLease(lease, queued, slot); acquired {
+				j.runWithLease(lease)
+			}
+		default:
+			logx.Infof("The previous run of synchronized job %q on node %q is still in progress, run it without lease",
+				j.id, prev.Owner)
+			j.run(j.ctx, scheduled)
+		}
+		return
+	}
+
+	j.runWithLease(lease)
+}
+
+// runWithLease runs the tick of an acquired lease, renewing the lease until the run has returned,
+// even if the job has been stopped in the meantime. The run is canceled if the lease is lost to another node.
+func (j *synchronizedJob) runWithLease(lease Lease) {
+	j.holdLease(j.leaseManager, j.leaseKey, j.options.leaseTTL, lease, func(ctx context.Context) {
+		j.run(ctx, time.UnixMilli(lease.Tick))
+	})
+}
+
+// holdLease calls fn while holding the acquired lease under key, renewing it every ttl/3,
+// and releases the lease afterwards. The context of fn is canceled if the lease is lost to another node.
+func (j *synchronizedJob) holdLease(manager LeaseManager, key string, ttl time.Duration, lease Lease,
+	fn func(ctx context.Context)) {
+	ctx, cancel := context.WithCancel(j.ctx)
+	defer cancel()
+	renewCtx, stopRenew := context.WithCancel(context.WithoutCancel(j.ctx))
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		j.renewLease(renewCtx, cancel, manager, key, ttl, lease)
+	}()
+
+	fn(ctx)
+	stopRenew()
+	<-done
+
+	if err := manager.ReleaseLeaseCtx(context.WithoutCancel(j.ctx), key, lease); err != nil {
+		logx.Errorf("Can not release the lease of synchronized job %q: %v", j.id, err)
+	}
+}
+
+func (j *synchronizedJob) renewLease(ctx context.Context, cancel context.CancelFunc, manager LeaseManager,
+	key string, ttl time.Duration, lease Lease) {
+	ticker := j.scheduler.clock.NewTicker(ttl / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.Chan():
+		}
+
+		lease.ExpiresAt = j.scheduler.clock.Now().Add(ttl).UnixMilli()
+		renewed, err := manager.RenewLeaseCtx(ctx, key, lease)
+		if err != nil {
+			logx.Errorf("Can not renew the lease of synchronized job %q: %v", j.id, err)
+			continue
+		}
+		if !renewed {
+			logx.Errorf("Synchronized job %q has lost its lease to another node, cancel it", j.id)
+			cancel()
+			return
+		}
+	}
+}
+
+// watchLease takes over the ticks whose lease has expired, until the job is stopped.
+func (j *synchronizedJob) watchLease() {
+	ticker := j.scheduler.clock.NewTicker(j.options.leaseTTL / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-j.ctx.Done():
+			return
+		case <-ticker.Chan():
+			j.takeOverLease()
+		}
+	}
+}
+
+func (j *synchronizedJob) takeOverLease() {
+	if !j.scheduler.tryAcquireSlot() {
+		return
+	}
+	defer j.scheduler.releaseSlot()
+
+	now := j.scheduler.clock.Now()
+	lease, ok, err := j.leaseManager.GetLeaseCtx(j.ctx, j.leaseKey)
+	if err != nil {
+		logx.Errorf("Can not get the lease of synchronized job %q: %v", j.id, err)
+		return
+	}
+	if !ok || lease.ExpiresAt > now.UnixMilli() {
+		return
+	}
+
+	newLease := Lease{
+		Owner:     j.scheduler.nodeID,
+		Tick:      lease.Tick,
+		ExpiresAt: now.Add(j.options.leaseTTL).UnixMilli(),
+	}
+	prev, acquired, err := j.leaseManager.AcquireLeaseCtx(j.ctx, j.leaseKey, newLease, now.UnixMilli())
+	if err != nil {
+		logx.Errorf("Can not acquire the lease of synchronized job %q: %v", j.id, err)
+		return
+	}
+	if !acquired {
+		return
+	}
+
+	scheduled := time.UnixMilli(newLease.Tick)
+	logx.Infof("Synchronized job %q of %s has expired on node %q, take it over",
+		j.id, scheduled.Format(time.RFC3339), prev.Owner)
+
+	switch j.options.leaseExpiredPolicy {
+	case LeaseExpiredFail:
+		err := fmt.Errorf("%w on node %q", ErrLeaseExpired, prev.Owner)
+		j.finish(withScheduledTime(j.ctx, scheduled), RunRecord{Start: now, End: now}, err)
+		if err := j.leaseManager.ReleaseLeaseCtx(j.ctx, j.leaseKey, newLease); err != nil {
+			logx.Errorf("Can not release the lease of synchronized job %q: %v", j.id, err)
+		}
+	default:
+		j.runWithLease(newLease)
+	}
+}
+
+// run runs the job in a span named after the job ID, retrying it according to the options of the job.
+func (j *synchronizedJob) run(ctx context.Context, scheduled time.Time) {
+	ctx = withScheduledTime(ctx, scheduled)
+	shardIndex, shardCount := Shard(ctx)
+	ctx, span := otel.Tracer(trace.TraceName).Start(ctx, j.id,
+		oteltrace.WithSpanKind(oteltrace.SpanKindInternal),
+		oteltrace.WithAttributes(
+			attribute.String("job.id", j.id),
+			attribute.String("job.node_id", j.scheduler.nodeID),
+			attribute.String("job.scheduled_time", scheduled.Format(time.RFC3339)),
+		))
+	if shardCount > 0 {
+		span.SetAttributes(attribute.Int("job.shard_index", shardIndex), attribute.Int("job.shard_count", shardCount))
+	}
+	defer span.End()
+	metricRunsWon.Inc(j.id)
+	logx.WithContext(ctx).Infof("Synchronized job %q of %s starts", j.id, scheduled.Format(time.RFC3339))
+
+	for attempt := 1; ; attempt++ {
+		record := RunRecord{Start: j.scheduler.clock.Now(), ShardIndex: shardIndex, ShardCount: shardCount}
+		if j.options.retryAttempts > 1 {
+			record.Attempt = attempt
+		}
+		err := j.attempt(withAttempt(ctx, attempt))
+		record.End = j.scheduler.clock.Now()
+		record.Duration = record.End.Sub(record.Start)
+		metricRunDuration.Observe(record.Duration.Milliseconds(), j.id)
+		j.report(ctx, record, err)
+
+		if err == nil || !j.retry(ctx, attempt, err) {
+			if err != nil {
+				span.RecordError(err)
+				span.SetStatus(codes.Error, err.Error())
+			}
+			span.SetAttributes(attribute.Int("job.attempts", attempt))
+			if shardCount == 0 && !isTriggered(ctx) {
+				j.recordCompletion(scheduled, err)
+			}
+			j.notify(err)
+			return
+		}
+	}
+}
+
+// attempt calls the job once, within the timeout of the job.
+func (j *synchronizedJob) attempt(ctx context.Context) error {
+	if j.options.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, j.options.timeout)
+		defer cancel()
+	}
+	return j.call(ctx)
+}
+
+// finish reports the result of a run.
+func (j *synchronizedJob) finish(ctx context.Context, record RunRecord, err error) {
+	j.report(ctx, record, err)
+	j.notify(err)
+}
+
+// report logs and records the result of an attempt of a run.
+func (j *synchronizedJob) report(ctx context.Context, record RunRecord, err error) {
+	var panicErr *PanicError
+	switch {
+	case errors.As(err, &panicErr):
+		record.Panic = fmt.Sprintf("%v\n%s", panicErr.Value, panicErr.Stack)
+		logx.WithContext(ctx).Errorf("Synchronized job %q panicked: %s", j.id, record.Panic)
+	case err != nil:
+		record.Error = err.Error()
+		logx.WithContext(ctx).Errorf("Synchronized job %q failed: %v", j.id, err)
+	}
+	j.record(record)
+}
+
+// notify passes the result of a run to the metrics, the error handler and the Hooks.
+func (j *synchronizedJob) notify(err error) {
+	if err != nil {
+		metricRunsFailed.Inc(j.id)
+		if j.options.errorHandler != nil {
+			j.options.errorHandler(j.id, err)
+		}
+		if j.scheduler.hooks.OnFailure != nil {
+			j.scheduler.hooks.OnFailure(j.id, err)
+		}
+	} else if j.scheduler.hooks.OnSuccess != nil {
+		j.scheduler.hooks.OnSuccess(j.id)
+	}
+}
+
+// call runs the job, converting a panic to a *PanicError.
+func (j *synchronizedJob) call(ctx context.Context) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = &PanicError{Value: p, Stack: debug.Stack()}
+		}
+	}()
+
+	return j.cb(ctx)
+}
+
+// record writes the RunRecord of a tick if the Scheduler has a RunRecorder.
+func (j *synchronizedJob) record(record RunRecord) {
+	recorder := j.scheduler.runRecorder
+	if recorder == nil {
+		return
+	}
+
+	record.JobID = j.id
+	record.NodeID = j.scheduler.nodeID
+	// The record of a canceled run is still worth writing.
+	if err := recorder.Record(context.WithoutCancel(j.ctx), record); err != nil {
+		logx.Errorf("Can not record the run of synchronized job %q: %v", j.id, err)
+	}
+}
+
+// stop removes the job, and resets its clocks, which are shared by the cluster.
+func (j *synchronizedJob) stop() {
+	j.remove()
+
+	j.clock.Reset()
+	for index := 0; index < j.options.shards; index++ {
+		j.shardClock(index).Reset()
+	}
+}
+
+// remove cancels the job and removes it from this node, leaving its clocks to the other nodes.
+func (j *synchronizedJob) remove() {
+	j.cancel()
+
+	j.scheduler.removeJob(j)
+
+	_ = j.scheduler.scheduler.RemoveJob(j.job.ID())
+}
@@ -1,9 +1,11 @@
 package session
 
+import "fmt"
+
 //lint:file-ignore SA5008 Use gozero config tags
 //nolint:staticcheck
 type SessionConfig struct {
-	SessionSecret           string // used to authenticate session cookies using HMAC
+	SessionSecret           string `json:",optional"` // used to authenticate session cookies using HMAC
 	SessionStorageNamespace string `json:",default=sessions"`
 	SessionCookieName       string `json:",default=SID"`
 	SessionCookiePath       string `json:",default=/"`
@@ -14,7 +16,69 @@ type SessionConfig struct {
 	SessionCookieSameSite string `json:",default=Lax,options=Strict|Lax|None"`
 	SessionCookieSecure   bool   `json:",default=false"`
 	// The session storage TTL is derived from its max age plus this grace period.
-	SessionStorageGracePeriod               int `json:",default=10,range=[1:60]"`
+	SessionStorageGracePeriod int `json:",default=10,range=[1:60]"`
+	// The duration in seconds that an unauthenticated session is stored, 0 for not at all.
+	// Unless it is 0, an unauthenticated session which has been issued a CSRF token is stored
+	// as long as the cookie is valid.
 	SessionStorageUnauthenticatedTTL        int `json:",default=60,range=[0:600]"`
 	SessionStorageInjectedAuthenticationTTL int `json:",default=0,range=[0:60]"`
+
+	// The backend storing the session values: redis, memory or sql.
+	// The sql backend stores them in the table named after the namespace.
+	SessionStorage string `json:",default=redis,options=redis|memory|sql"`
+	// The xorm driver name and data source of the sql backend. The driver must be imported by the App.
+	SessionStorageDriver     string `json:",optional"`
+	SessionStorageDataSource string `json:",optional"`
+
+	// The secrets authenticating the session cookies using HMAC, newest first, which replace SessionSecret.
+	// The cookies authenticated by any of them are accepted, and re-issued with the newest one.
+	// To rotate the secret, prepend a new one, and remove the oldest once its cookies have expired.
+	SessionSecrets []string `json:",optional"`
+	// The keys encrypting the session cookies using AES, paired with the secrets by index.
+	// A secret without a key, or with an empty one, does not encrypt. The keys are 16, 24 or 32 bytes.
+	SessionEncryptionKeys []string `json:",optional"`
+}
+
+// keyPairs returns the pairs of the authentication and encryption keys of the session cookies, newest first.
+func (c SessionConfig) keyPairs() ([][]byte, error) {
+	secrets := c.SessionSecrets
+	if len(secrets) == 0 {
+		secrets = []string{c.SessionSecret}
+	}
+	if len(c.SessionEncryptionKeys) > len(secrets) {
+		return nil, fmt.Errorf("expect no more session encryption keys than session secrets")
+	}
+
+	pairs := make([][]byte, 0, 2*len(secrets))
+	for i, secret := range secrets {
+		if len(secret) != 32 {
+			return nil, fmt.Errorf("expect a session secret of 32 bytes")
+		}
+		var key []byte
+		if i < len(c.SessionEncryptionKeys) && c.SessionEncryptionKeys[i] != "" {
+			key = []byte(c.SessionEncryptionKeys[i])
+			switch len(key) {
+			case 16, 24, 32:
+			default:
+				return nil, fmt.Errorf("expect a session encryption key of 16, 24 or 32 bytes")
+			}
+		}
+		pairs = append(pairs, []byte(secret), key)
+	}
+	return pairs, nil
+}
+
+// CSRFConfig configures the CSRFMiddleware.
+//
+//nolint:staticcheck
+type CSRFConfig struct {
+	// The form field and the request header carrying the token. The header is also set on the responses.
+	CSRFFieldName  string `json:",default=csrf_token"`
+	CSRFHeaderName string `json:",default=X-CSRF-Token"`
+	// The paths which are not checked, e.g. webhooks. A path ending with * matches its prefix.
+	CSRFExemptPaths []string `json:",optional"`
+	// Whether the Origin or Referer header of an unsafe request must be the host of the request
+	// or one of the trusted origins, given by host or by URL.
+	CSRFCheckOrigin    bool     `json:",default=true"`
+	CSRFTrustedOrigins []string `json:",optional"`
 }
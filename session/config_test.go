@@ -0,0 +1,82 @@
+package session
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/gorilla/securecookie"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	testOldSecret = "fedcba9876543210fedcba9876543210"
+	testKey       = "0123456789abcdef"
+)
+
+func TestKeyPairs(t *testing.T) {
+	pairs, err := SessionConfig{SessionSecret: testSecret}.keyPairs()
+	assert.NoError(t, err)
+	assert.Equal(t, [][]byte{[]byte(testSecret), nil}, pairs)
+
+	pairs, err = SessionConfig{
+		SessionSecret:         "ignored",
+		SessionSecrets:        []string{testSecret, testOldSecret},
+		SessionEncryptionKeys: []string{testKey},
+	}.keyPairs()
+	assert.NoError(t, err)
+	assert.Equal(t, [][]byte{[]byte(testSecret), []byte(testKey), []byte(testOldSecret), nil}, pairs)
+
+	_, err = SessionConfig{SessionSecrets: []string{testSecret, "short"}}.keyPairs()
+	assert.Error(t, err)
+	_, err = SessionConfig{SessionSecrets: []string{testSecret}, SessionEncryptionKeys: []string{"short"}}.keyPairs()
+	assert.Error(t, err)
+	_, err = SessionConfig{SessionSecrets: []string{testSecret}, SessionEncryptionKeys: []string{testKey, testKey}}.keyPairs()
+	assert.Error(t, err)
+	_, err = SessionConfig{}.keyPairs()
+	assert.Error(t, err)
+}
+
+func TestSecretRotation(t *testing.T) {
+	backend := NewSessionStoreMemory()
+	c := SessionConfig{
+		SessionSecret:                    testOldSecret,
+		SessionCookieName:                "SID",
+		SessionCookiePath:                "/",
+		SessionCookieTTL:                 600,
+		SessionStorageGracePeriod:        10,
+		SessionStorageUnauthenticatedTTL: 60,
+	}
+	SetupStore(c, backend)
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		s := From(r.Context())
+		s.Set("count", s.GetInt("count")+1)
+	}
+	oldToken, oldID := tokenID(t, request(Middleware("pro")(handler), "/", ""))
+
+	// Rotate the secret, encrypting the cookies from now on.
+	c.SessionSecrets = []string{testSecret, testOldSecret}
+	c.SessionEncryptionKeys = []string{testKey}
+	SetupStore(c, backend)
+	var count int64
+	w := request(Middleware("pro")(func(w http.ResponseWriter, r *http.Request) {
+		handler(w, r)
+		count = From(r.Context()).GetInt("count")
+	}), "/", oldToken)
+	assert.Equal(t, int64(2), count)
+
+	// The cookie is re-issued with the newest secret.
+	newToken, id := tokenID(t, w)
+	assert.Equal(t, oldID, id)
+	assert.NotEqual(t, oldToken, newToken)
+	cookies := readCookiesByHeaderName(http.Header{"Cookie": {newToken}}, "Cookie", "SID")
+	newCodecs := securecookie.CodecsFromPairs([]byte(testSecret), []byte(testKey))
+	assert.NoError(t, securecookie.DecodeMulti("SID", cookies[0].Value, &id, newCodecs...))
+	oldCodecs := securecookie.CodecsFromPairs([]byte(testOldSecret))
+	assert.Error(t, securecookie.DecodeMulti("SID", cookies[0].Value, &id, oldCodecs...))
+
+	// Once the old secret is removed, its cookies start new sessions.
+	c.SessionSecrets = c.SessionSecrets[:1]
+	SetupStore(c, backend)
+	_, id = tokenID(t, request(Middleware("pro")(handler), "/", oldToken))
+	assert.NotEqual(t, oldID, id)
+}
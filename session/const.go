@@ -27,6 +27,8 @@ const (
 	UserAgent = "user_agent"
 	// The user's IP address
 	UserIPAddr = "user_ipaddr"
+	// The secret of the synchronizer tokens against cross-site request forgery
+	CSRFSecret = "csrf_secret"
 )
 
 // GetUserAddr from the http request, considering X-Forwarded-For, Forwarded
@@ -15,22 +15,43 @@ import (
 	"github.com/zeromicro/go-zero/core/service"
 	"github.com/zeromicro/go-zero/core/stores/redis"
 	"github.com/zeromicro/go-zero/rest"
+	"xorm.io/xorm"
 )
 
 var (
 	sessionContextKey contextKey
-	sessionStore      *redisStore
+	sessionStore      *cookieStore
 	sessionConfig     SessionConfig
 )
 
 type contextKey struct{}
 
+// Setup sets up the sessions in the backend selected by c.SessionStorage.
+// store is only used by the redis backend, and may be nil for the others.
 func Setup(c SessionConfig, store *redis.Redis) {
-	if len(c.SessionSecret) != 32 {
-		logx.Must(fmt.Errorf("expect a session secret of 32 bytes"))
+	var backend SessionStore
+	switch c.SessionStorage {
+	case "memory":
+		backend = NewSessionStoreMemory()
+	case "sql":
+		engine, err := xorm.NewEngine(c.SessionStorageDriver, c.SessionStorageDataSource)
+		logx.Must(err)
+		backend = NewSessionStoreXorm(engine, c.SessionStorageNamespace)
+	default:
+		if store == nil {
+			logx.Must(fmt.Errorf("expect a redis store for the redis session storage"))
+		}
+		backend = NewSessionStoreRedis(store, c.SessionStorageNamespace)
 	}
+	SetupStore(c, backend)
+}
+
+// SetupStore sets up the sessions in backend, regardless of c.SessionStorage.
+func SetupStore(c SessionConfig, backend SessionStore) {
+	keyPairs, err := c.keyPairs()
+	logx.Must(err)
 
-	sessionStore = newRedisStore(store, c)
+	sessionStore = newCookieStore(backend, keyPairs, c)
 	sessionConfig = c
 }
 
@@ -92,7 +113,15 @@ func Middleware(serviceConfMode string) rest.Middleware {
 			logFields = append(logFields, logx.Field("path", r.URL.Path))
 			logc.Infow(r.Context(), "[Session]", logFields...)
 
-			next(w, r.WithContext(context.WithValue(r.Context(), sessionContextKey, session)))
+			// Regenerate the session once the handler has authenticated it, to prevent session fixation.
+			lw := &loginWriter{
+				ResponseWriter: w,
+				session:        session,
+				id:             session.ID,
+				authenticated:  (Session{session}).Authenticated(),
+			}
+			next(lw, r.WithContext(context.WithValue(r.Context(), sessionContextKey, session)))
+			lw.regenerateOnLogin()
 
 			// Update session values. Put these after `next` to prevent from changing by handlers.
 			// Actually inside `next` we are reading them as `LastUpdated` and `LastPath`
@@ -101,17 +130,22 @@ func Middleware(serviceConfMode string) rest.Middleware {
 			}
 			session.Values[Updated] = time.Now().Format(time.RFC3339)
 			session.Values[Path] = r.URL.Path
-			// Use a relatively short age for unauthenticated session, to save capacity of redis storage
+			// Use a relatively short age for unauthenticated session, to save capacity of the session storage
 			if (Session{session}).GetInt(Authenticated) == 0 {
 				session.Options.MaxAge = sessionConfig.SessionStorageUnauthenticatedTTL
+				// But keep the CSRF secret as long as the forms issued with its tokens may be submitted.
+				if _, ok := session.Values[CSRFSecret]; ok && session.Options.MaxAge > 0 {
+					session.Options.MaxAge = max(session.Options.MaxAge, sessionConfig.SessionCookieTTL)
+				}
 			}
 			if injectedAuthentication {
 				session.Options.MaxAge = sessionConfig.SessionStorageInjectedAuthenticationTTL
 			}
 
-			err = sessionStore.save(session)
+			// The values are saved even if the client has gone away in the meantime.
+			err = sessionStore.save(context.WithoutCancel(r.Context()), session)
 			if err != nil {
-				logx.Errorf("Can not write session.Values to redis: %v", err)
+				logx.Errorf("Can not write session.Values to the session storage: %v", err)
 			}
 		}
 	}
@@ -222,7 +256,9 @@ func (s Session) UpdatedAt() time.Time {
 // while client could initiate a session with a same ID, which he remembered in the past.
 // ForceDelete is usually used to forbid a session programmly, maybe upon the user's password change.
 func ForceDelete(sessionID string) {
-	sessionStore.erase(&sessions.Session{ID: sessionID})
+	if err := sessionStore.erase(context.Background(), sessionID); err != nil {
+		logx.Errorf("Can not delete session %q: %v", sessionID, err)
+	}
 }
 
 func (s Session) AddFlash(value interface{}, vars ...string) {
@@ -0,0 +1,223 @@
+package session
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gorilla/securecookie"
+	"github.com/zeromicro/go-zero/core/logc"
+	"github.com/zeromicro/go-zero/rest"
+)
+
+var (
+	// ErrCSRFTokenMissing means an unsafe request carries no CSRF token.
+	ErrCSRFTokenMissing = errors.New("session: CSRF token missing")
+	// ErrCSRFTokenInvalid means an unsafe request carries a CSRF token which is not of its session.
+	ErrCSRFTokenInvalid = errors.New("session: CSRF token invalid")
+	// ErrCSRFOriginInvalid means an unsafe request comes from an origin which is not trusted.
+	ErrCSRFOriginInvalid = errors.New("session: CSRF origin invalid")
+)
+
+const (
+	csrfSecretLength       = 32
+	defaultCSRFFieldName   = "csrf_token"
+	defaultCSRFHeaderName  = "X-CSRF-Token"
+	csrfTemplateFieldInput = `<input type="hidden" name="%s" value="%s">`
+)
+
+type csrfContextKey struct{}
+
+// CSRFMiddleware protects the unsafe requests, whose methods are not GET, HEAD, OPTIONS or TRACE,
+// against cross-site request forgery. It must be used inside Middleware.
+//
+// The secret of a session is created once a token is issued by CSRFToken or CSRFTemplateField,
+// or once the session is authenticated, so that the anonymous requests do not store a session each.
+// The responses of a session with a secret carry a token in the CSRFHeaderName header. An unsafe request
+// must send a token back in the same header or in the CSRFFieldName form field, unless its path is exempt,
+// so an unsafe request of a session without a secret is rejected. The tokens are masked differently
+// each time, so they do not leak the secret through compressed responses. The secret is replaced when
+// the session is regenerated, e.g. upon login, and the token in the header of that response is of
+// the new secret. The secret keeps an unauthenticated session stored for SessionCookieTTL instead of
+// SessionStorageUnauthenticatedTTL, unless that is 0, so the anonymous forms stay valid as long.
+// With CSRFCheckOrigin, the Origin header of an unsafe request, or its Referer header if it has
+// no Origin, must also be the host of the request or a trusted origin.
+func CSRFMiddleware(c CSRFConfig) rest.Middleware {
+	if c.CSRFFieldName == "" {
+		c.CSRFFieldName = defaultCSRFFieldName
+	}
+	if c.CSRFHeaderName == "" {
+		c.CSRFHeaderName = defaultCSRFHeaderName
+	}
+
+	return func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			session := From(r.Context())
+			secret := storedCSRFSecret(session)
+			if secret == nil && session.Authenticated() {
+				secret = csrfSecretOf(session)
+			}
+			if secret != nil {
+				w.Header().Set(c.CSRFHeaderName, maskCSRFSecret(secret))
+			}
+			if lw := findLoginWriter(w); lw != nil {
+				lw.csrfHeaderName = c.CSRFHeaderName
+			}
+
+			if !isSafeMethod(r.Method) && !isCSRFExempt(c.CSRFExemptPaths, r.URL.Path) {
+				if err := checkCSRF(c, r, secret); err != nil {
+					logc.Errorf(r.Context(), "Reject %s %s: %v", r.Method, r.URL.Path, err)
+					http.Error(w, err.Error(), http.StatusForbidden)
+					return
+				}
+			}
+
+			next(w, r.WithContext(context.WithValue(r.Context(), csrfContextKey{}, c)))
+		}
+	}
+}
+
+// CSRFToken returns a token of the session in ctx, to be sent back in the form field
+// or the header of CSRFMiddleware. Each call returns a different token of the same secret.
+func CSRFToken(ctx context.Context) string {
+	return maskCSRFSecret(csrfSecretOf(From(ctx)))
+}
+
+// CSRFTemplateField returns a hidden input of a token of the session in ctx, for the html forms.
+func CSRFTemplateField(ctx context.Context) template.HTML {
+	fieldName := defaultCSRFFieldName
+	if c, ok := ctx.Value(csrfContextKey{}).(CSRFConfig); ok {
+		fieldName = c.CSRFFieldName
+	}
+	return template.HTML(fmt.Sprintf(csrfTemplateFieldInput, template.HTMLEscapeString(fieldName), CSRFToken(ctx)))
+}
+
+// CSRFFuncs returns the template functions csrfToken and csrfField, which are CSRFToken and CSRFTemplateField.
+// Add them to the templates before parsing them by embedx.Template, and call them with the context
+// of the request in the template data, e.g. {{ csrfField .Ctx }}.
+func CSRFFuncs() template.FuncMap {
+	return template.FuncMap{
+		"csrfToken": CSRFToken,
+		"csrfField": CSRFTemplateField,
+	}
+}
+
+// csrfSecretOf returns the CSRF secret of a session, creating it if the session has none.
+func csrfSecretOf(s Session) []byte {
+	secret := storedCSRFSecret(s)
+	if secret == nil {
+		secret = securecookie.GenerateRandomKey(csrfSecretLength)
+		s.Set(CSRFSecret, base64.RawURLEncoding.EncodeToString(secret))
+	}
+	return secret
+}
+
+// storedCSRFSecret returns the CSRF secret of a session, or nil if the session has none.
+func storedCSRFSecret(s Session) []byte {
+	secret, err := base64.RawURLEncoding.DecodeString(s.GetStr(CSRFSecret))
+	if err != nil || len(secret) != csrfSecretLength {
+		return nil
+	}
+	return secret
+}
+
+// maskCSRFSecret returns a token of a random mask followed by the secret XORed with the mask.
+func maskCSRFSecret(secret []byte) string {
+	token := securecookie.GenerateRandomKey(2 * len(secret))
+	for i, b := range secret {
+		token[len(secret)+i] = token[i] ^ b
+	}
+	return base64.RawURLEncoding.EncodeToString(token)
+}
+
+// validCSRFToken reports whether token is a masked secret, comparing them in constant time.
+func validCSRFToken(token string, secret []byte) bool {
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil || len(b) != 2*len(secret) {
+		return false
+	}
+	unmasked := make([]byte, len(secret))
+	for i := range unmasked {
+		unmasked[i] = b[i] ^ b[len(secret)+i]
+	}
+	return subtle.ConstantTimeCompare(unmasked, secret) == 1
+}
+
+func checkCSRF(c CSRFConfig, r *http.Request, secret []byte) error {
+	if c.CSRFCheckOrigin {
+		if err := checkOrigin(r, c.CSRFTrustedOrigins); err != nil {
+			return err
+		}
+	}
+
+	token := r.Header.Get(c.CSRFHeaderName)
+	if token == "" {
+		token = r.PostFormValue(c.CSRFFieldName)
+	}
+	if token == "" {
+		return ErrCSRFTokenMissing
+	}
+	if secret == nil || !validCSRFToken(token, secret) {
+		return ErrCSRFTokenInvalid
+	}
+	return nil
+}
+
+// checkOrigin checks the Origin header of a request, or its Referer header if it has no Origin.
+// A request with neither is left to the token.
+func checkOrigin(r *http.Request, trustedOrigins []string) error {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		origin = r.Referer()
+		if origin == "" {
+			return nil
+		}
+	}
+	u, err := url.Parse(origin)
+	if err != nil || u.Host == "" {
+		return fmt.Errorf("%w: %q", ErrCSRFOriginInvalid, origin)
+	}
+	if strings.EqualFold(u.Host, r.Host) {
+		return nil
+	}
+	for _, trusted := range trustedOrigins {
+		if strings.Contains(trusted, "://") {
+			if tu, err := url.Parse(trusted); err == nil {
+				trusted = tu.Host
+			}
+		}
+		if strings.EqualFold(u.Host, trusted) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %q", ErrCSRFOriginInvalid, u.Host)
+}
+
+func isSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	default:
+		return false
+	}
+}
+
+// isCSRFExempt reports whether path is one of paths, or has the prefix of one ending with *.
+func isCSRFExempt(paths []string, path string) bool {
+	for _, p := range paths {
+		if prefix, ok := strings.CutSuffix(p, "*"); ok {
+			if strings.HasPrefix(path, prefix) {
+				return true
+			}
+		} else if p == path {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,198 @@
+package session
+
+import (
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newCSRFTestHandler(t *testing.T) http.HandlerFunc {
+	setupTestSessions(t)
+	csrf := CSRFMiddleware(CSRFConfig{
+		CSRFFieldName:      "csrf_token",
+		CSRFHeaderName:     "X-CSRF-Token",
+		CSRFExemptPaths:    []string{"/webhook", "/hooks/*"},
+		CSRFCheckOrigin:    true,
+		CSRFTrustedOrigins: []string{"https://app.example.com"},
+	})
+	return Middleware("pro")(csrf(csrfTestHandler))
+}
+
+// csrfTestHandler writes a token on /form, and ok otherwise.
+func csrfTestHandler(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/form" {
+		_, _ = w.Write([]byte(CSRFToken(r.Context())))
+		return
+	}
+	_, _ = w.Write([]byte("ok"))
+}
+
+func csrfRequest(handler http.HandlerFunc, method, path, token string, header http.Header,
+	form url.Values) *httptest.ResponseRecorder {
+	var r *http.Request
+	if form != nil {
+		r = httptest.NewRequest(method, path, strings.NewReader(form.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	} else {
+		r = httptest.NewRequest(method, path, nil)
+	}
+	r.Header.Set("Session-Token", token)
+	for k, v := range header {
+		r.Header[k] = v
+	}
+	w := httptest.NewRecorder()
+	handler(w, r)
+	return w
+}
+
+func TestCSRFMiddleware(t *testing.T) {
+	handler := newCSRFTestHandler(t)
+	w := request(handler, "/form", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	session, _ := tokenID(t, w)
+	csrfToken := w.Body.String()
+	assert.NotEmpty(t, csrfToken)
+
+	// The tokens are masked differently, but of the same secret.
+	w = request(handler, "/", session)
+	otherToken := w.Header().Get("X-CSRF-Token")
+	assert.NotEmpty(t, otherToken)
+	assert.NotEqual(t, csrfToken, otherToken)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		header http.Header
+		form   url.Values
+		code   int
+	}{
+		{"safe", http.MethodGet, "/", nil, nil, http.StatusOK},
+		{"missing", http.MethodPost, "/", nil, nil, http.StatusForbidden},
+		{"header", http.MethodPost, "/", http.Header{"X-Csrf-Token": {csrfToken}}, nil, http.StatusOK},
+		{"form", http.MethodPost, "/", nil, url.Values{"csrf_token": {otherToken}}, http.StatusOK},
+		{"invalid", http.MethodDelete, "/", http.Header{"X-Csrf-Token": {csrfToken[1:]}}, nil, http.StatusForbidden},
+		{"exempt", http.MethodPost, "/webhook", nil, nil, http.StatusOK},
+		{"exempt prefix", http.MethodPost, "/hooks/github", nil, nil, http.StatusOK},
+		{"not exempt", http.MethodPost, "/webhook/github", nil, nil, http.StatusForbidden},
+		{"same origin", http.MethodPost, "/", http.Header{
+			"X-Csrf-Token": {csrfToken}, "Origin": {"http://example.com"}}, nil, http.StatusOK},
+		{"trusted origin", http.MethodPost, "/", http.Header{
+			"X-Csrf-Token": {csrfToken}, "Origin": {"https://app.example.com"}}, nil, http.StatusOK},
+		{"cross origin", http.MethodPost, "/", http.Header{
+			"X-Csrf-Token": {csrfToken}, "Origin": {"https://evil.com"}}, nil, http.StatusForbidden},
+		{"cross referer", http.MethodPost, "/", http.Header{
+			"X-Csrf-Token": {csrfToken}, "Referer": {"https://evil.com/form"}}, nil, http.StatusForbidden},
+		{"null origin", http.MethodPost, "/", http.Header{
+			"X-Csrf-Token": {csrfToken}, "Origin": {"null"}}, nil, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := csrfRequest(handler, tt.method, tt.path, session, tt.header, tt.form)
+			assert.Equal(t, tt.code, w.Code, w.Body.String())
+		})
+	}
+
+	// The token of another session is rejected, whether the session has a secret or not.
+	other, _ := tokenID(t, request(handler, "/form", ""))
+	w = csrfRequest(handler, http.MethodPost, "/", other, http.Header{"X-Csrf-Token": {csrfToken}}, nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	other, _ = tokenID(t, request(handler, "/", ""))
+	w = csrfRequest(handler, http.MethodPost, "/", other, http.Header{"X-Csrf-Token": {csrfToken}}, nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestCSRFTemplateField(t *testing.T) {
+	setupTestSessions(t)
+	tmpl := template.Must(template.New("form").Funcs(CSRFFuncs()).Parse(`<form>{{ csrfField . }}</form>`))
+	var body string
+	handler := Middleware("pro")(CSRFMiddleware(CSRFConfig{CSRFFieldName: "_csrf"})(
+		func(w http.ResponseWriter, r *http.Request) {
+			var sb strings.Builder
+			assert.NoError(t, tmpl.Execute(&sb, r.Context()))
+			body = sb.String()
+		}))
+	w := request(handler, "/", "")
+
+	assert.True(t, strings.HasPrefix(body, `<form><input type="hidden" name="_csrf" value="`), body)
+	token := strings.TrimSuffix(strings.TrimPrefix(body, `<form><input type="hidden" name="_csrf" value="`), `"></form>`)
+	session, _ := tokenID(t, w)
+	w = csrfRequest(handler, http.MethodPost, "/", session, nil, url.Values{"_csrf": {token}})
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestCSRFRegenerate(t *testing.T) {
+	setupTestSessions(t)
+	handler := Middleware("pro")(CSRFMiddleware(CSRFConfig{})(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/login" {
+			s := From(r.Context())
+			s.Set(UserID, 1)
+			s.Set(Authenticated, 1)
+		}
+		csrfTestHandler(w, r)
+	}))
+	w := request(handler, "/form", "")
+	anonymous, _ := tokenID(t, w)
+	anonymousToken := w.Body.String()
+
+	// The login issues a fresh secret, whose token is in the header of the login response.
+	w = csrfRequest(handler, http.MethodPost, "/login", anonymous, http.Header{"X-Csrf-Token": {anonymousToken}}, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	session, _ := tokenID(t, w)
+	loginToken := w.Header().Get("X-CSRF-Token")
+
+	w = csrfRequest(handler, http.MethodPost, "/", session, http.Header{"X-Csrf-Token": {anonymousToken}}, nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = csrfRequest(handler, http.MethodPost, "/", session, http.Header{"X-Csrf-Token": {loginToken}}, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestCSRFAnonymousSession(t *testing.T) {
+	backend := setupTestSessions(t)
+	now := time.Now()
+	backend.now = func() time.Time { return now }
+	handler := Middleware("pro")(CSRFMiddleware(CSRFConfig{})(csrfTestHandler))
+
+	// An anonymous request which is not issued a token has no secret, and is stored as briefly as usual.
+	w := request(handler, "/", "")
+	session, id := tokenID(t, w)
+	assert.Empty(t, w.Header().Get("X-CSRF-Token"))
+	assert.NotContains(t, backend.sessions[id].values, CSRFSecret)
+	assert.Equal(t, now.Add(70*time.Second), backend.sessions[id].expiresAt)
+	w = csrfRequest(handler, http.MethodPost, "/submit", session, nil, nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	// The anonymous session holding the secret is kept as long as its cookie, not SessionStorageUnauthenticatedTTL.
+	w = request(handler, "/form", session)
+	csrfToken := w.Body.String()
+	assert.Equal(t, now.Add(610*time.Second), backend.sessions[id].expiresAt)
+	now = now.Add(5 * time.Minute)
+	w = csrfRequest(handler, http.MethodPost, "/submit", session, http.Header{"X-Csrf-Token": {csrfToken}}, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestCSRFAuthenticatedSession(t *testing.T) {
+	setupTestSessions(t)
+	handler := Middleware("pro")(CSRFMiddleware(CSRFConfig{})(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/login" {
+			s := From(r.Context())
+			s.Set(UserID, 1)
+			s.Set(Authenticated, 1)
+		}
+	}))
+
+	// The login response carries the token of the secret created upon regeneration.
+	w := request(handler, "/login", "")
+	session, _ := tokenID(t, w)
+	csrfToken := w.Header().Get("X-CSRF-Token")
+	assert.NotEmpty(t, csrfToken)
+	assert.NotEmpty(t, request(handler, "/", session).Header().Get("X-CSRF-Token"))
+	w = csrfRequest(handler, http.MethodPost, "/", session, http.Header{"X-Csrf-Token": {csrfToken}}, nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
@@ -0,0 +1,16 @@
+local key = KEYS[1]
+local expire_time = ARGV[1]
+
+-- Only replace the values of an existing session, which may have been erased in the meantime
+if redis.call('EXISTS', key) == 0 then
+    return 0
+end
+
+-- Remove the first argument from ARGV which is the expire time
+table.remove(ARGV, 1)
+
+redis.call('DEL', key)
+redis.call('HMSET', key, unpack(ARGV))
+redis.call('EXPIRE', key, expire_time)
+
+return 1
@@ -0,0 +1,123 @@
+package session
+
+import (
+	"context"
+	"maps"
+	"sync"
+	"time"
+)
+
+var (
+	_ SessionStore     = (*sessionStoreMemory)(nil)
+	_ SessionUpdater   = (*sessionStoreMemory)(nil)
+	_ UserSessionIndex = (*sessionStoreMemory)(nil)
+)
+
+// memorySweepInterval is how often the expired sessions are evicted from a sessionStoreMemory.
+const memorySweepInterval = time.Minute
+
+type memorySession struct {
+	values    map[string]string
+	expiresAt time.Time
+}
+
+// sessionStoreMemory stores the sessions in the memory of the process.
+type sessionStoreMemory struct {
+	mu        sync.Mutex
+	sessions  map[string]memorySession
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// NewSessionStoreMemory returns a SessionStore which keeps the sessions in the memory of the process,
+// for tests and single-node deployments. The sessions are lost when the process exits.
+// The expired sessions read as absent, and are evicted by the next Save after a minute.
+func NewSessionStoreMemory() SessionStore {
+	return &sessionStoreMemory{
+		sessions: make(map[string]memorySession),
+		now:      time.Now,
+	}
+}
+
+func (s *sessionStoreMemory) Load(_ context.Context, id string) (map[string]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	session, ok := s.sessions[id]
+	if !ok || !session.expiresAt.After(s.now()) {
+		return nil, ErrSessionNotFound
+	}
+	return maps.Clone(session.values), nil
+}
+
+func (s *sessionStoreMemory) Save(_ context.Context, id string, values map[string]string, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	s.sweep(now)
+	if len(values) == 0 {
+		delete(s.sessions, id)
+		return nil
+	}
+	s.sessions[id] = memorySession{values: maps.Clone(values), expiresAt: now.Add(ttl)}
+	return nil
+}
+
+func (s *sessionStoreMemory) Update(_ context.Context, id string, values map[string]string, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	if session, ok := s.sessions[id]; !ok || !session.expiresAt.After(now) {
+		return ErrSessionNotFound
+	}
+	if len(values) == 0 {
+		delete(s.sessions, id)
+		return nil
+	}
+	s.sessions[id] = memorySession{values: maps.Clone(values), expiresAt: now.Add(ttl)}
+	return nil
+}
+
+func (s *sessionStoreMemory) Erase(_ context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.sessions, id)
+	return nil
+}
+
+func (s *sessionStoreMemory) Touch(_ context.Context, id string, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	if session, ok := s.sessions[id]; ok && session.expiresAt.After(now) {
+		session.expiresAt = now.Add(ttl)
+		s.sessions[id] = session
+	}
+	return nil
+}
+
+// UserSessions scans all the sessions, which is fine for the sizes the memory store is meant for.
+func (s *sessionStoreMemory) UserSessions(_ context.Context, userID string) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	var ids []string
+	for id, session := range s.sessions {
+		if session.expiresAt.After(now) && userIDOf(session.values) == userID {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+// sweep evicts the expired sessions, at most once per memorySweepInterval. The caller must hold s.mu.
+func (s *sessionStoreMemory) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < memorySweepInterval {
+		return
+	}
+	s.lastSweep = now
+	for id, session := range s.sessions {
+		if !session.expiresAt.After(now) {
+			delete(s.sessions, id)
+		}
+	}
+}
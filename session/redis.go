@@ -1,173 +1,167 @@
 package session
 
 import (
+	"context"
 	_ "embed"
-	"encoding/base32"
-	"net/http"
+	"errors"
+	"time"
 
-	"github.com/gorilla/securecookie"
-	"github.com/gorilla/sessions"
-	"github.com/zeromicro/go-zero/core/jsonx"
 	"github.com/zeromicro/go-zero/core/logx"
 	"github.com/zeromicro/go-zero/core/stores/redis"
 )
 
-var _ sessions.Store = (*redisStore)(nil)
+var (
+	_ SessionStore     = (*sessionStoreRedis)(nil)
+	_ SessionUpdater   = (*sessionStoreRedis)(nil)
+	_ UserSessionIndex = (*sessionStoreRedis)(nil)
+)
 
 var (
 	//go:embed hmsetex.lua
 	hmsetExLua    string
 	hmsetExScript = redis.NewScript(hmsetExLua)
+	//go:embed hmsetxx.lua
+	hmsetXxLua    string
+	hmsetXxScript = redis.NewScript(hmsetXxLua)
+	//go:embed sadd_expire.lua
+	saddExpireLua    string
+	saddExpireScript = redis.NewScript(saddExpireLua)
 )
 
-// redisStore stores sessions in the redis.
-type redisStore struct {
-	Codecs     []securecookie.Codec
-	Options    *sessions.Options // default configuration
-	store      *redis.Redis
-	namespace  string
-	gracePerid int
+// sessionStoreRedis stores each session in a redis hash, and the IDs of the sessions of each user in a redis set.
+type sessionStoreRedis struct {
+	store     *redis.Redis
+	namespace string
 }
 
-func newRedisStore(store *redis.Redis, c SessionConfig) *redisStore {
-	rs := &redisStore{
-		Codecs: securecookie.CodecsFromPairs([]byte(c.SessionSecret)),
-		Options: &sessions.Options{
-			Path:     c.SessionCookiePath,
-			Domain:   c.SessionCookieDomain,
-			MaxAge:   c.SessionCookieTTL,
-			SameSite: parseSameSite(c.SessionCookieSameSite),
-			Secure:   c.SessionCookieSecure,
-			HttpOnly: true,
-		},
-		store:      store,
-		namespace:  c.SessionStorageNamespace + ":",
-		gracePerid: c.SessionStorageGracePeriod,
-	}
-
-	rs.MaxAge(rs.Options.MaxAge)
-	return rs
+// NewSessionStoreRedis returns a SessionStore which stores each session in a redis hash,
+// keyed by the session ID prefixed with namespace. It indexes the sessions by user
+// in redis sets, which expire with the last of their sessions.
+func NewSessionStoreRedis(store *redis.Redis, namespace string) SessionStore {
+	return &sessionStoreRedis{
+		store:     store,
+		namespace: namespace + ":",
+	}
 }
 
-// MaxAge sets the maximum age for the store and the underlying cookie
-// implementation. Individual sessions can be deleted by setting Options.MaxAge
-// = -1 for that session.
-func (s *redisStore) MaxAge(age int) {
-	s.Options.MaxAge = age
+func (s *sessionStoreRedis) getUserKey(userID string) string {
+	return s.namespace + "user:" + userID
+}
 
-	// Set the maxAge for each securecookie instance.
-	for _, codec := range s.Codecs {
-		if sc, ok := codec.(*securecookie.SecureCookie); ok {
-			sc.MaxAge(age)
-		}
+func (s *sessionStoreRedis) Load(ctx context.Context, id string) (map[string]string, error) {
+	fvs, err := s.store.HgetallCtx(ctx, s.namespace+id)
+	if err != nil {
+		return nil, err
 	}
+	if len(fvs) == 0 {
+		return nil, ErrSessionNotFound
+	}
+	return fvs, nil
 }
 
-// Get returns a session for the given name after adding it to the registry.
-func (s *redisStore) Get(r *http.Request, name string) (*sessions.Session, error) {
-	return sessions.GetRegistry(r).Get(s, name)
+func (s *sessionStoreRedis) Save(ctx context.Context, id string, values map[string]string, ttl time.Duration) error {
+	return s.save(ctx, hmsetExScript, id, values, ttl)
 }
 
-// New returns a session for the given name without adding it to the registry.
-func (s *redisStore) New(r *http.Request, name string) (*sessions.Session, error) {
-	session := sessions.NewSession(s, name)
-	opts := *s.Options
-	session.Options = &opts
-	session.IsNew = true
-	var err error
-	if c, errCookie := Token(r, name); errCookie == nil {
-		err = securecookie.DecodeMulti(name, c.Value, &session.ID, s.Codecs...)
-		if err == nil {
-			if err := s.load(session); err == nil {
-				session.IsNew = false
-			}
-		}
-	}
-	return session, err
+func (s *sessionStoreRedis) Update(ctx context.Context, id string, values map[string]string, ttl time.Duration) error {
+	return s.save(ctx, hmsetXxScript, id, values, ttl)
 }
 
-var base32RawStdEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
-
-// Save adds a single session to the response.
-//
-// If the Options.MaxAge of the session is <= 0 then the session item will be
-// deleted from the redis. With this process it enforces the properly
-// session cookie handling so no need to trust in the cookie management in the
-// web browser.
-func (s *redisStore) Save(r *http.Request, w http.ResponseWriter, session *sessions.Session) error {
-	// Delete if max-age is <= 0
-	if session.Options.MaxAge <= 0 {
-		if err := s.erase(session); err != nil {
-			return err
-		}
-		SetToken(w, sessions.NewCookie(session.Name(), "", session.Options))
-		return nil
+// save writes the values of a session by script, which returns 0 if it has not written them.
+func (s *sessionStoreRedis) save(ctx context.Context, script *redis.Script, id string, values map[string]string,
+	ttl time.Duration) error {
+	if len(values) == 0 {
+		return s.Erase(ctx, id)
 	}
 
-	if session.ID == "" {
-		// Because the ID is used in the filename, encode it to
-		// use alphanumeric characters only.
-		session.ID = base32RawStdEncoding.EncodeToString(
-			securecookie.GenerateRandomKey(32))
+	user := userIDOf(values)
+	if err := s.unindex(ctx, id, user); err != nil {
+		return err
+	}
+	args := make([]any, 0, 1+len(values)*2)
+	args = append(args, int(ttl/time.Second))
+	for k, v := range values {
+		args = append(args, k, v)
 	}
-	// Don't save to the store until the middleware finished.
-	// if err := s.save(session); err != nil {
-	// 	return err
-	// }
-	encoded, err := securecookie.EncodeMulti(session.Name(), session.ID,
-		s.Codecs...)
+	written, err := s.store.ScriptRunCtx(ctx, script, []string{s.namespace + id}, args...)
 	if err != nil {
 		return err
 	}
-	SetToken(w, sessions.NewCookie(session.Name(), encoded, session.Options))
-	return nil
+	if written == int64(0) {
+		return ErrSessionNotFound
+	}
+	return s.index(ctx, id, user, ttl)
 }
 
-// save writes encoded session.Values to redis.
-func (s *redisStore) save(session *sessions.Session) error {
-	// Deleted if max-age is <= 0
-	if session.Options.MaxAge <= 0 {
-		return nil
+func (s *sessionStoreRedis) Erase(ctx context.Context, id string) error {
+	if err := s.unindex(ctx, id, ""); err != nil {
+		return err
 	}
-	if len(session.Values) == 0 {
-		return nil
+	_, err := s.store.DelCtx(ctx, s.namespace+id)
+	return err
+}
+
+func (s *sessionStoreRedis) Touch(ctx context.Context, id string, ttl time.Duration) error {
+	if err := s.store.ExpireCtx(ctx, s.namespace+id, int(ttl/time.Second)); err != nil {
+		return err
+	}
+	user, err := s.indexedUser(ctx, id)
+	if err != nil {
+		return err
+	}
+	return s.index(ctx, id, user, ttl)
+}
+
+func (s *sessionStoreRedis) UserSessions(ctx context.Context, userID string) ([]string, error) {
+	key := s.getUserKey(userID)
+	ids, err := s.store.SmembersCtx(ctx, key)
+	if err != nil {
+		return nil, err
 	}
 
-	args := make([]any, 0, 1+len(session.Values)*2)
-	args = append(args, session.Options.MaxAge+s.gracePerid)
-	for k, v := range session.Values {
-		if sk, ok := k.(string); ok {
-			if sv, err := jsonx.MarshalToString(v); err == nil {
-				args = append(args, sk, sv)
-			}
+	// Remove the sessions which have expired from the index.
+	live := ids[:0]
+	for _, id := range ids {
+		exists, err := s.store.ExistsCtx(ctx, s.namespace+id)
+		if err != nil {
+			return nil, err
+		}
+		if exists {
+			live = append(live, id)
+		} else if _, err := s.store.SremCtx(ctx, key, id); err != nil {
+			logx.Errorf("Can not remove expired session %q from the index of user %q: %v", id, userID, err)
 		}
 	}
-	_, err := s.store.ScriptRun(hmsetExScript, []string{s.namespace + session.ID}, args...)
-	return err
+	return live, nil
 }
 
-// load reads from redis and decodes its content into session.Values.
-func (s *redisStore) load(session *sessions.Session) error {
-	fvs, err := s.store.Hgetall(s.namespace + session.ID)
+// indexedUser returns the user of a stored session, or an empty string if there is none.
+func (s *sessionStoreRedis) indexedUser(ctx context.Context, id string) (string, error) {
+	encoded, err := s.store.HgetCtx(ctx, s.namespace+id, UserID)
+	if errors.Is(err, redis.Nil) {
+		return "", nil
+	}
 	if err != nil {
-		return err
+		return "", err
 	}
-	if len(fvs) == 0 {
-		return redis.Nil
-	}
-	for k, v := range fvs {
-		var iv interface{}
-		if err := jsonx.UnmarshalFromString(v, &iv); err == nil {
-			session.Values[k] = iv
-		} else {
-			logx.Errorw("Invalid Session Value", logx.Field("SessionID", session.ID), logx.Field("Key", k), logx.Field("Value", v))
-		}
+	return userIDOf(map[string]string{UserID: encoded}), nil
+}
+
+// index adds a session to the index of its user, which is kept at least as long as the session.
+func (s *sessionStoreRedis) index(ctx context.Context, id, user string, ttl time.Duration) error {
+	if user == "" {
+		return nil
 	}
-	return nil
+	_, err := s.store.ScriptRunCtx(ctx, saddExpireScript, []string{s.getUserKey(user)}, id, int(ttl/time.Second))
+	return err
 }
 
-// delete session item
-func (s *redisStore) erase(session *sessions.Session) error {
-	_, err := s.store.Del(s.namespace + session.ID)
+// unindex removes a session from the index of its stored user, unless the user is newUser.
+func (s *sessionStoreRedis) unindex(ctx context.Context, id, newUser string) error {
+	user, err := s.indexedUser(ctx, id)
+	if err != nil || user == "" || user == newUser {
+		return err
+	}
+	_, err = s.store.SremCtx(ctx, s.getUserKey(user), id)
 	return err
 }
@@ -0,0 +1,165 @@
+package session
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/securecookie"
+	"github.com/gorilla/sessions"
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+// Regenerate moves the session values to a fresh random ID and re-issues the cookie and token,
+// to prevent session fixation upon a privilege change. The old ID stays valid for the grace period
+// of the session storage, so that the concurrent requests still carrying it do not lose the session,
+// but the old session is never saved again, so it expires at the end of the grace period.
+// It must be called before the response is written. The middleware calls it automatically
+// once the session is authenticated.
+func (s Session) Regenerate(w http.ResponseWriter) error {
+	return sessionStore.regenerate(context.Background(), w, s.s)
+}
+
+func newSessionID() string {
+	// Because the ID is used in the filename, encode it to
+	// use alphanumeric characters only.
+	return base32RawStdEncoding.EncodeToString(securecookie.GenerateRandomKey(32))
+}
+
+// regenerate moves a session to a fresh ID and re-issues its cookie. The values are saved under the new ID
+// at once, for the requests which may carry it before the middleware saves them again.
+// The CSRF secret is replaced as well, so that the tokens issued before the privilege change are rejected.
+func (s *cookieStore) regenerate(ctx context.Context, w http.ResponseWriter, session *sessions.Session) error {
+	oldID := session.ID
+	newID := newSessionID()
+	encoded, err := securecookie.EncodeMulti(session.Name(), newID, s.Codecs...)
+	if err != nil {
+		return err
+	}
+	oldSecret, hadSecret := session.Values[CSRFSecret]
+	delete(session.Values, CSRFSecret)
+	wasSuperseded := superseded(session)
+	delete(session.Values, supersededKey)
+	session.ID = newID
+	if err := s.write(ctx, session, false); err != nil {
+		session.ID = oldID
+		if hadSecret {
+			session.Values[CSRFSecret] = oldSecret
+		}
+		if wasSuperseded {
+			session.Values[supersededKey] = true
+		}
+		return err
+	}
+	resetToken(w, sessions.NewCookie(session.Name(), encoded, session.Options))
+	if lw := findLoginWriter(w); lw != nil && lw.csrfHeaderName != "" {
+		w.Header().Set(lw.csrfHeaderName, maskCSRFSecret(csrfSecretOf(Session{session})))
+	}
+
+	if oldID != "" {
+		if err := s.supersede(ctx, oldID); err != nil {
+			logx.Errorf("Can not expire regenerated session %q: %v", oldID, err)
+		}
+	}
+	return nil
+}
+
+// supersededKey marks a session which has been regenerated, so that it is neither saved nor re-issued.
+const supersededKey = "superseded"
+
+// supersede marks the old session of a regenerated one, keeping its values for the grace period.
+func (s *cookieStore) supersede(ctx context.Context, id string) error {
+	if s.gracePeriod <= 0 {
+		return s.backend.Erase(ctx, id)
+	}
+	values, err := s.backend.Load(ctx, id)
+	if errors.Is(err, ErrSessionNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	values[supersededKey] = "true"
+	ttl := time.Duration(s.gracePeriod) * time.Second
+	if updater, ok := s.backend.(SessionUpdater); ok {
+		if err = updater.Update(ctx, id, values, ttl); errors.Is(err, ErrSessionNotFound) {
+			return nil
+		}
+		return err
+	}
+	return s.backend.Save(ctx, id, values, ttl)
+}
+
+// superseded reports whether the session has been regenerated by another request.
+func superseded(session *sessions.Session) bool {
+	_, ok := session.Values[supersededKey]
+	return ok
+}
+
+// loginWriter regenerates the session once it is authenticated, before the response is written.
+type loginWriter struct {
+	http.ResponseWriter
+	session *sessions.Session
+	// id and authenticated are the ID of the session and whether it is authenticated before the handler.
+	id            string
+	authenticated bool
+	checked       bool
+	// csrfHeaderName is the header of the CSRF tokens, which are re-issued upon regeneration.
+	csrfHeaderName string
+}
+
+// findLoginWriter returns the loginWriter which w is or wraps, or nil if there is none.
+func findLoginWriter(w http.ResponseWriter) *loginWriter {
+	for {
+		switch v := w.(type) {
+		case *loginWriter:
+			return v
+		case interface{ Unwrap() http.ResponseWriter }:
+			w = v.Unwrap()
+		default:
+			return nil
+		}
+	}
+}
+
+// regenerateOnLogin regenerates the session if the handler has authenticated it, and has not regenerated it.
+// Only the first call checks, as the token can not be re-issued once the response is written.
+func (w *loginWriter) regenerateOnLogin() {
+	if w.checked {
+		return
+	}
+	w.checked = true
+	if w.authenticated || w.session.ID != w.id || !(Session{w.session}).Authenticated() {
+		return
+	}
+	if err := sessionStore.regenerate(context.Background(), w, w.session); err != nil {
+		logx.Errorf("Can not regenerate session %q: %v", w.id, err)
+	}
+}
+
+func (w *loginWriter) WriteHeader(statusCode int) {
+	w.regenerateOnLogin()
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (w *loginWriter) Write(b []byte) (int, error) {
+	w.regenerateOnLogin()
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *loginWriter) Flush() {
+	w.regenerateOnLogin()
+	_ = http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+func (w *loginWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return http.NewResponseController(w.ResponseWriter).Hijack()
+}
+
+// Unwrap returns the underlying http.ResponseWriter, for http.ResponseController.
+func (w *loginWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,139 @@
+package session
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/securecookie"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupTestSessions(t *testing.T) *sessionStoreMemory {
+	backend := NewSessionStoreMemory()
+	SetupStore(SessionConfig{
+		SessionSecret:                    testSecret,
+		SessionCookieName:                "SID",
+		SessionCookiePath:                "/",
+		SessionCookieTTL:                 600,
+		SessionStorageGracePeriod:        10,
+		SessionStorageUnauthenticatedTTL: 60,
+	}, backend)
+	return backend.(*sessionStoreMemory)
+}
+
+// tokenID returns the session ID of the only token of a response.
+func tokenID(t *testing.T, w *httptest.ResponseRecorder) (token, id string) {
+	tokens := w.Header().Values("Set-Session-Token")
+	if !assert.Len(t, tokens, 1) {
+		t.FailNow()
+	}
+	assert.Len(t, w.Header().Values("Set-Cookie"), 1)
+	cookies := readCookiesByHeaderName(http.Header{"Cookie": tokens}, "Cookie", "SID")
+	if !assert.Len(t, cookies, 1) {
+		t.FailNow()
+	}
+	assert.NoError(t, securecookie.DecodeMulti("SID", cookies[0].Value, &id, sessionStore.Codecs...))
+	return tokens[0], id
+}
+
+func request(handler http.HandlerFunc, path, token string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodGet, path, nil)
+	if token != "" {
+		r.Header.Set("Session-Token", token)
+	}
+	w := httptest.NewRecorder()
+	handler(w, r)
+	return w
+}
+
+func TestRegenerateOnLogin(t *testing.T) {
+	backend := setupTestSessions(t)
+	handler := Middleware("pro")(func(w http.ResponseWriter, r *http.Request) {
+		s := From(r.Context())
+		switch r.URL.Path {
+		case "/login":
+			s.Set(UserID, 1)
+			s.Set(Authenticated, 1)
+			_, _ = w.Write([]byte("welcome"))
+		case "/login-silently":
+			s.Set(UserID, 2)
+			s.Set(Authenticated, 1)
+		}
+	})
+
+	anonymous, anonymousID := tokenID(t, request(handler, "/", ""))
+	w := request(handler, "/login", anonymous)
+	assert.Equal(t, "welcome", w.Body.String())
+	authenticated, authenticatedID := tokenID(t, w)
+	assert.NotEqual(t, anonymousID, authenticatedID)
+
+	values, err := backend.Load(context.Background(), authenticatedID)
+	assert.NoError(t, err)
+	assert.Equal(t, "1", values[Authenticated])
+	// The old ID expires after the grace period.
+	values, err = backend.Load(context.Background(), anonymousID)
+	assert.NoError(t, err)
+	assert.Empty(t, values[Authenticated])
+	assert.WithinDuration(t, time.Now().Add(10*time.Second), backend.sessions[anonymousID].expiresAt, time.Second)
+
+	// The authenticated session keeps its ID.
+	_, id := tokenID(t, request(handler, "/", authenticated))
+	assert.Equal(t, authenticatedID, id)
+
+	// The session is regenerated even if the handler does not write the response.
+	anonymous, anonymousID = tokenID(t, request(handler, "/", ""))
+	_, id = tokenID(t, request(handler, "/login-silently", anonymous))
+	assert.NotEqual(t, anonymousID, id)
+}
+
+func TestRegenerateSuperseded(t *testing.T) {
+	backend := setupTestSessions(t)
+	handler := Middleware("pro")(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/login" {
+			s := From(r.Context())
+			s.Set(UserID, 1)
+			s.Set(Authenticated, 1)
+		}
+	})
+
+	anonymous, anonymousID := tokenID(t, request(handler, "/", ""))
+	request(handler, "/login", anonymous)
+	expiresAt := backend.sessions[anonymousID].expiresAt
+
+	// A request still carrying the old ID keeps the session during the grace period,
+	// but neither re-issues nor extends it.
+	w := request(handler, "/", anonymous)
+	assert.Empty(t, w.Header().Values("Set-Session-Token"))
+	assert.Empty(t, w.Header().Values("Set-Cookie"))
+	assert.Equal(t, expiresAt, backend.sessions[anonymousID].expiresAt)
+
+	// Logging in with the old ID regenerates it again.
+	_, id := tokenID(t, request(handler, "/login", anonymous))
+	assert.NotEqual(t, anonymousID, id)
+	values, err := backend.Load(context.Background(), id)
+	assert.NoError(t, err)
+	assert.NotContains(t, values, supersededKey)
+}
+
+func TestRegenerate(t *testing.T) {
+	backend := setupTestSessions(t)
+	handler := Middleware("pro")(func(w http.ResponseWriter, r *http.Request) {
+		s := From(r.Context())
+		s.Set(UserID, 1)
+		s.Set(Authenticated, 1)
+		if r.URL.Path == "/sudo" {
+			s.Set(UserType, "admin")
+			assert.NoError(t, s.Regenerate(w))
+		}
+	})
+
+	token, loginID := tokenID(t, request(handler, "/", ""))
+	_, id := tokenID(t, request(handler, "/sudo", token))
+	assert.NotEqual(t, loginID, id)
+	values, err := backend.Load(context.Background(), id)
+	assert.NoError(t, err)
+	assert.Equal(t, `"admin"`, values[UserType])
+}
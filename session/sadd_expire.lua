@@ -0,0 +1,13 @@
+local key = KEYS[1]
+local member = ARGV[1]
+local expire_time = tonumber(ARGV[2])
+
+redis.call('SADD', key, member)
+
+-- Only extend the expiration time, as the set is shared by the sessions of the user
+local ttl = redis.call('TTL', key)
+if ttl < expire_time then
+    redis.call('EXPIRE', key, expire_time)
+end
+
+return "OK"
@@ -0,0 +1,217 @@
+package session
+
+import (
+	"context"
+	"encoding/base32"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/securecookie"
+	"github.com/gorilla/sessions"
+	"github.com/zeromicro/go-zero/core/jsonx"
+	"github.com/zeromicro/go-zero/core/logc"
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+// ErrSessionNotFound is returned by SessionStore.Load if the session does not exist or has expired.
+var ErrSessionNotFound = errors.New("session: session not found")
+
+// SessionStore is the storage backend of the sessions, keyed by session ID.
+// The values of a session are stored as JSON encoded strings keyed by their names.
+type SessionStore interface {
+	// Load returns the values of a session, or ErrSessionNotFound if it does not exist or has expired.
+	Load(ctx context.Context, id string) (map[string]string, error)
+	// Save replaces the values of a session, which expire ttl after.
+	Save(ctx context.Context, id string, values map[string]string, ttl time.Duration) error
+	// Erase deletes a session. Erasing a session which does not exist is not an error.
+	Erase(ctx context.Context, id string) error
+	// Touch makes a session expire ttl after, keeping its values.
+	// Touching a session which does not exist is not an error.
+	Touch(ctx context.Context, id string, ttl time.Duration) error
+}
+
+// SessionUpdater is implemented by the SessionStores which can replace the values of a session
+// only if it still exists, so that a request in progress does not bring back a session
+// which has been erased in the meantime, e.g. by RevokeUserSessions.
+type SessionUpdater interface {
+	// Update is like Save, but returns ErrSessionNotFound without saving anything
+	// if the session does not exist or has expired.
+	Update(ctx context.Context, id string, values map[string]string, ttl time.Duration) error
+}
+
+var _ sessions.Store = (*cookieStore)(nil)
+
+// cookieStore keeps the session IDs in the cookies or tokens, and the session values in a SessionStore.
+type cookieStore struct {
+	Codecs      []securecookie.Codec
+	Options     *sessions.Options // default configuration
+	backend     SessionStore
+	gracePeriod int
+}
+
+// newCookieStore returns a cookieStore whose cookies are encoded with the first of keyPairs,
+// and decoded with any of them.
+func newCookieStore(backend SessionStore, keyPairs [][]byte, c SessionConfig) *cookieStore {
+	cs := &cookieStore{
+		Codecs: securecookie.CodecsFromPairs(keyPairs...),
+		Options: &sessions.Options{
+			Path:     c.SessionCookiePath,
+			Domain:   c.SessionCookieDomain,
+			MaxAge:   c.SessionCookieTTL,
+			SameSite: parseSameSite(c.SessionCookieSameSite),
+			Secure:   c.SessionCookieSecure,
+			HttpOnly: true,
+		},
+		backend:     backend,
+		gracePeriod: c.SessionStorageGracePeriod,
+	}
+
+	cs.MaxAge(cs.Options.MaxAge)
+	return cs
+}
+
+// MaxAge sets the maximum age for the store and the underlying cookie
+// implementation. Individual sessions can be deleted by setting Options.MaxAge
+// = -1 for that session.
+func (s *cookieStore) MaxAge(age int) {
+	s.Options.MaxAge = age
+
+	// Set the maxAge for each securecookie instance.
+	for _, codec := range s.Codecs {
+		if sc, ok := codec.(*securecookie.SecureCookie); ok {
+			sc.MaxAge(age)
+		}
+	}
+}
+
+// Get returns a session for the given name after adding it to the registry.
+func (s *cookieStore) Get(r *http.Request, name string) (*sessions.Session, error) {
+	return sessions.GetRegistry(r).Get(s, name)
+}
+
+// New returns a session for the given name without adding it to the registry.
+// A token which can not be decoded, e.g. one authenticated by a removed secret, starts a new session.
+func (s *cookieStore) New(r *http.Request, name string) (*sessions.Session, error) {
+	session := sessions.NewSession(s, name)
+	opts := *s.Options
+	session.Options = &opts
+	session.IsNew = true
+	if c, errCookie := Token(r, name); errCookie == nil {
+		if err := securecookie.DecodeMulti(name, c.Value, &session.ID, s.Codecs...); err != nil {
+			logc.Infof(r.Context(), "Can not decode session token, start a new session: %v", err)
+			session.ID = ""
+		} else if err := s.load(r.Context(), session); err == nil {
+			session.IsNew = false
+		}
+	}
+	return session, nil
+}
+
+var base32RawStdEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// Save adds a single session to the response.
+//
+// If the Options.MaxAge of the session is <= 0 then the session item will be
+// deleted from the backend. With this process it enforces the properly
+// session cookie handling so no need to trust in the cookie management in the
+// web browser.
+func (s *cookieStore) Save(r *http.Request, w http.ResponseWriter, session *sessions.Session) error {
+	// Delete if max-age is <= 0
+	if session.Options.MaxAge <= 0 {
+		if err := s.erase(r.Context(), session.ID); err != nil {
+			return err
+		}
+		SetToken(w, sessions.NewCookie(session.Name(), "", session.Options))
+		return nil
+	}
+
+	if session.ID == "" {
+		session.ID = newSessionID()
+	}
+	// The client may hold the token of the regenerated session already.
+	if superseded(session) {
+		return nil
+	}
+	// Don't save to the store until the middleware finished.
+	// if err := s.save(session); err != nil {
+	// 	return err
+	// }
+	encoded, err := securecookie.EncodeMulti(session.Name(), session.ID,
+		s.Codecs...)
+	if err != nil {
+		return err
+	}
+	SetToken(w, sessions.NewCookie(session.Name(), encoded, session.Options))
+	return nil
+}
+
+// ttl returns how long the backend keeps a session, which is its max age plus the grace period.
+func (s *cookieStore) ttl(session *sessions.Session) time.Duration {
+	return time.Duration(session.Options.MaxAge+s.gracePeriod) * time.Second
+}
+
+// save writes encoded session.Values to the backend. A session which is not new is only updated
+// if the backend is a SessionUpdater and the session still exists, so that a session revoked
+// while its request is in progress stays revoked.
+func (s *cookieStore) save(ctx context.Context, session *sessions.Session) error {
+	return s.write(ctx, session, !session.IsNew)
+}
+
+// write writes encoded session.Values to the backend, only if the session still exists if update is true.
+func (s *cookieStore) write(ctx context.Context, session *sessions.Session, update bool) error {
+	// Deleted if max-age is <= 0
+	if session.Options.MaxAge <= 0 {
+		return nil
+	}
+	if len(session.Values) == 0 {
+		return nil
+	}
+	if superseded(session) {
+		logx.Infof("Session %q has been regenerated during the request, do not save it", session.ID)
+		return nil
+	}
+
+	values := make(map[string]string, len(session.Values))
+	for k, v := range session.Values {
+		if sk, ok := k.(string); ok {
+			if sv, err := jsonx.MarshalToString(v); err == nil {
+				values[sk] = sv
+			}
+		}
+	}
+	if updater, ok := s.backend.(SessionUpdater); ok && update {
+		err := updater.Update(ctx, session.ID, values, s.ttl(session))
+		if errors.Is(err, ErrSessionNotFound) {
+			logx.Infof("Session %q has been erased during the request, do not save it", session.ID)
+			return nil
+		}
+		return err
+	}
+	return s.backend.Save(ctx, session.ID, values, s.ttl(session))
+}
+
+// load reads from the backend and decodes its content into session.Values.
+func (s *cookieStore) load(ctx context.Context, session *sessions.Session) error {
+	fvs, err := s.backend.Load(ctx, session.ID)
+	if err != nil {
+		return err
+	}
+	for k, v := range fvs {
+		var iv interface{}
+		if err := jsonx.UnmarshalFromString(v, &iv); err == nil {
+			session.Values[k] = iv
+		} else {
+			logx.Errorw("Invalid Session Value", logx.Field("SessionID", session.ID), logx.Field("Key", k), logx.Field("Value", v))
+		}
+	}
+	return nil
+}
+
+// delete session item
+func (s *cookieStore) erase(ctx context.Context, id string) error {
+	if id == "" {
+		return nil
+	}
+	return s.backend.Erase(ctx, id)
+}
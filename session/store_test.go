@@ -0,0 +1,221 @@
+package session
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/zeromicro/go-zero/core/stores/redis"
+	_ "modernc.org/sqlite"
+	"xorm.io/xorm"
+)
+
+const testSecret = "0123456789abcdef0123456789abcdef"
+
+func testSessionStore(t *testing.T, store SessionStore) {
+	ctx := context.Background()
+	_ = store.Erase(ctx, "test-session")
+
+	_, err := store.Load(ctx, "test-session")
+	assert.ErrorIs(t, err, ErrSessionNotFound)
+
+	values := map[string]string{UserID: "1", Username: `"alice"`}
+	assert.NoError(t, store.Save(ctx, "test-session", values, time.Minute))
+	loaded, err := store.Load(ctx, "test-session")
+	assert.NoError(t, err)
+	assert.Equal(t, values, loaded)
+
+	// Save replaces the values.
+	values = map[string]string{UserID: "2"}
+	assert.NoError(t, store.Save(ctx, "test-session", values, time.Minute))
+	loaded, err = store.Load(ctx, "test-session")
+	assert.NoError(t, err)
+	assert.Equal(t, values, loaded)
+
+	assert.NoError(t, store.Touch(ctx, "test-session", time.Hour))
+	loaded, err = store.Load(ctx, "test-session")
+	assert.NoError(t, err)
+	assert.Equal(t, values, loaded)
+
+	assert.NoError(t, store.Erase(ctx, "test-session"))
+	_, err = store.Load(ctx, "test-session")
+	assert.ErrorIs(t, err, ErrSessionNotFound)
+	assert.NoError(t, store.Erase(ctx, "test-session"))
+	assert.NoError(t, store.Touch(ctx, "test-session", time.Hour))
+}
+
+func testSessionUpdater(t *testing.T, store SessionStore) {
+	ctx := context.Background()
+	updater := store.(SessionUpdater)
+	_ = store.Erase(ctx, "test-update")
+
+	// Update does not bring back a session which does not exist.
+	values := map[string]string{UserID: "1"}
+	assert.ErrorIs(t, updater.Update(ctx, "test-update", values, time.Minute), ErrSessionNotFound)
+	_, err := store.Load(ctx, "test-update")
+	assert.ErrorIs(t, err, ErrSessionNotFound)
+
+	assert.NoError(t, store.Save(ctx, "test-update", values, time.Minute))
+	values = map[string]string{UserID: "1", Username: `"alice"`}
+	assert.NoError(t, updater.Update(ctx, "test-update", values, time.Minute))
+	loaded, err := store.Load(ctx, "test-update")
+	assert.NoError(t, err)
+	assert.Equal(t, values, loaded)
+	assert.NoError(t, updater.Update(ctx, "test-update", values, time.Minute))
+
+	assert.NoError(t, store.Erase(ctx, "test-update"))
+	assert.ErrorIs(t, updater.Update(ctx, "test-update", values, time.Minute), ErrSessionNotFound)
+	_, err = store.Load(ctx, "test-update")
+	assert.ErrorIs(t, err, ErrSessionNotFound)
+}
+
+func testUserSessionIndex(t *testing.T, store SessionStore) {
+	ctx := context.Background()
+	index := store.(UserSessionIndex)
+	for _, id := range []string{"test-user-a", "test-user-b", "test-user-c", "test-anonymous"} {
+		_ = store.Erase(ctx, id)
+	}
+
+	assert.NoError(t, store.Save(ctx, "test-user-a", map[string]string{UserID: "42"}, time.Minute))
+	assert.NoError(t, store.Save(ctx, "test-user-b", map[string]string{UserID: `"42"`}, time.Minute))
+	assert.NoError(t, store.Save(ctx, "test-user-c", map[string]string{UserID: "43"}, time.Minute))
+	assert.NoError(t, store.Save(ctx, "test-anonymous", map[string]string{Path: `"/"`}, time.Minute))
+	ids, err := index.UserSessions(ctx, "42")
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"test-user-a", "test-user-b"}, ids)
+
+	// A session changing user moves to the index of the new user.
+	assert.NoError(t, store.Save(ctx, "test-user-b", map[string]string{UserID: "43"}, time.Minute))
+	ids, err = index.UserSessions(ctx, "42")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"test-user-a"}, ids)
+	ids, err = index.UserSessions(ctx, "43")
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"test-user-b", "test-user-c"}, ids)
+
+	assert.NoError(t, store.Erase(ctx, "test-user-a"))
+	ids, err = index.UserSessions(ctx, "42")
+	assert.NoError(t, err)
+	assert.Empty(t, ids)
+
+	for _, id := range []string{"test-user-b", "test-user-c", "test-anonymous"} {
+		assert.NoError(t, store.Erase(ctx, id))
+	}
+}
+
+func TestSessionStoreMemory(t *testing.T) {
+	store := NewSessionStoreMemory()
+	testSessionStore(t, store)
+	testSessionUpdater(t, store)
+	testUserSessionIndex(t, store)
+
+	now := time.Now()
+	memory := store.(*sessionStoreMemory)
+	memory.now = func() time.Time { return now }
+	ctx := context.Background()
+	assert.NoError(t, store.Save(ctx, "test-expired", map[string]string{UserID: "1"}, time.Minute))
+	assert.NoError(t, store.Save(ctx, "test-touched", map[string]string{UserID: "2"}, time.Minute))
+	now = now.Add(30 * time.Second)
+	assert.NoError(t, store.Touch(ctx, "test-touched", time.Minute))
+
+	now = now.Add(45 * time.Second)
+	_, err := store.Load(ctx, "test-expired")
+	assert.ErrorIs(t, err, ErrSessionNotFound)
+	_, err = store.Load(ctx, "test-touched")
+	assert.NoError(t, err)
+
+	// The next Save evicts the expired session.
+	now = now.Add(memorySweepInterval)
+	assert.NoError(t, store.Save(ctx, "test-other", map[string]string{UserID: "3"}, time.Minute))
+	assert.NotContains(t, memory.sessions, "test-expired")
+	assert.NotContains(t, memory.sessions, "test-touched")
+	assert.Contains(t, memory.sessions, "test-other")
+}
+
+func TestSessionStoreRedis(t *testing.T) {
+	r := redis.MustNewRedis(redis.RedisConf{Host: "127.0.0.1:6379", Type: "node"})
+	store := NewSessionStoreRedis(r, "TestSessionStoreRedis")
+	testSessionStore(t, store)
+	testSessionUpdater(t, store)
+	testUserSessionIndex(t, store)
+
+	ctx := context.Background()
+	assert.NoError(t, store.Save(ctx, "test-ttl", map[string]string{UserID: "1"}, time.Minute))
+	defer func() { _ = store.Erase(ctx, "test-ttl") }()
+	ttl, err := r.Ttl("TestSessionStoreRedis:test-ttl")
+	assert.NoError(t, err)
+	assert.Equal(t, 60, ttl)
+	assert.NoError(t, store.Touch(ctx, "test-ttl", time.Hour))
+	ttl, err = r.Ttl("TestSessionStoreRedis:test-ttl")
+	assert.NoError(t, err)
+	assert.Equal(t, 3600, ttl)
+
+	// The index of a user expires with the last of the sessions, and drops the expired ones.
+	assert.NoError(t, store.Save(ctx, "test-index-long", map[string]string{UserID: "44"}, time.Hour))
+	assert.NoError(t, store.Save(ctx, "test-index-short", map[string]string{UserID: "44"}, time.Minute))
+	ttl, err = r.Ttl("TestSessionStoreRedis:user:44")
+	assert.NoError(t, err)
+	assert.Equal(t, 3600, ttl)
+	_, err = r.Del("TestSessionStoreRedis:test-index-short")
+	assert.NoError(t, err)
+	ids, err := store.(UserSessionIndex).UserSessions(ctx, "44")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"test-index-long"}, ids)
+	members, err := r.Smembers("TestSessionStoreRedis:user:44")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"test-index-long"}, members)
+	assert.NoError(t, store.Erase(ctx, "test-index-long"))
+}
+
+func TestSessionStoreXorm(t *testing.T) {
+	engine, err := xorm.NewEngine("sqlite", filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = engine.Close() })
+	store := NewSessionStoreXorm(engine, "sessions")
+	testSessionStore(t, store)
+	testSessionUpdater(t, store)
+	testUserSessionIndex(t, store)
+
+	now := time.Now()
+	xormStore := store.(*sessionStoreXorm)
+	xormStore.now = func() time.Time { return now }
+	ctx := context.Background()
+	assert.NoError(t, store.Save(ctx, "test-expired", map[string]string{UserID: "1"}, time.Minute))
+	now = now.Add(time.Minute)
+	_, err = store.Load(ctx, "test-expired")
+	assert.ErrorIs(t, err, ErrSessionNotFound)
+
+	// The next Save deletes the expired session.
+	now = now.Add(xormSweepInterval)
+	assert.NoError(t, store.Save(ctx, "test-other", map[string]string{UserID: "2"}, time.Minute))
+	exists, err := engine.Table("sessions").ID("test-expired").Exist(new(sessionRow))
+	assert.NoError(t, err)
+	assert.False(t, exists)
+}
+
+func TestMiddlewareMemory(t *testing.T) {
+	setupTestSessions(t)
+
+	var counts []int64
+	handler := Middleware("pro")(func(w http.ResponseWriter, r *http.Request) {
+		s := From(r.Context())
+		s.Set("count", s.GetInt("count")+1)
+		counts = append(counts, s.GetInt("count"))
+	})
+
+	w := httptest.NewRecorder()
+	handler(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	token := w.Header().Get("Set-Session-Token")
+	assert.NotEmpty(t, token)
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Session-Token", token)
+	handler(httptest.NewRecorder(), r)
+	assert.Equal(t, []int64{1, 2}, counts)
+}
@@ -33,6 +33,26 @@ func SetToken(w http.ResponseWriter, cookie *http.Cookie) {
 	}
 }
 
+// resetToken is like SetToken, but replaces the token and the cookie of the same name set before.
+func resetToken(w http.ResponseWriter, cookie *http.Cookie) {
+	prefix := cookie.Name + "="
+	for _, header := range []string{"Set-Session-Token", "Set-Cookie"} {
+		lines := w.Header()[header]
+		kept := lines[:0]
+		for _, line := range lines {
+			if !strings.HasPrefix(line, prefix) {
+				kept = append(kept, line)
+			}
+		}
+		if len(kept) > 0 {
+			w.Header()[header] = kept
+		} else {
+			w.Header().Del(header)
+		}
+	}
+	SetToken(w, cookie)
+}
+
 func readCookiesByHeaderName(h http.Header, header, filter string) []*http.Cookie {
 	lines := h[header]
 	if len(lines) == 0 {
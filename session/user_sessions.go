@@ -0,0 +1,140 @@
+package session
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/zeromicro/go-zero/core/jsonx"
+	"github.com/zeromicro/go-zero/core/lang"
+	"github.com/zeromicro/go-zero/core/logx"
+)
+
+// ErrUserIndexNotSupported means the SessionStore does not index the sessions by user.
+var ErrUserIndexNotSupported = errors.New("session: the session storage does not index the sessions by user")
+
+// UserSessionIndex is implemented by the SessionStores which index the sessions by the UserID value,
+// keeping the index consistent as the sessions are saved, erased and expired.
+type UserSessionIndex interface {
+	// UserSessions returns the IDs of the live sessions of a user, whose ID is the string form
+	// of the UserID value. The IDs may include sessions which have just expired or changed user.
+	UserSessions(ctx context.Context, userID string) ([]string, error)
+}
+
+// UserSession describes a live session of a user.
+type UserSession struct {
+	ID        string
+	Created   time.Time
+	Updated   time.Time
+	UserAgent string
+	UserAddr  string
+}
+
+// ListUserSessions returns the live sessions of a user, the most recently updated first.
+// userID is compared with the UserID value of the sessions by their string forms, so both
+// an int64 and its decimal string find the sessions of the same user.
+func ListUserSessions(ctx context.Context, userID any) ([]UserSession, error) {
+	sessions, err := loadUserSessions(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]UserSession, 0, len(sessions))
+	for id, values := range sessions {
+		// A regenerated session only lingers for the grace period.
+		if _, ok := values[supersededKey]; ok {
+			continue
+		}
+		list = append(list, UserSession{
+			ID:        id,
+			Created:   parseTime(values[Created]),
+			Updated:   parseTime(values[Updated]),
+			UserAgent: decodeString(values[UserAgent]),
+			UserAddr:  decodeString(values[UserIPAddr]),
+		})
+	}
+	slices.SortFunc(list, func(a, b UserSession) int { return b.Updated.Compare(a.Updated) })
+	return list, nil
+}
+
+// RevokeUserSessions deletes the sessions of a user except the one of exceptID, and returns how many
+// are deleted. It is usually used upon the user's password change, or to sign out the other devices
+// with the ID of the current session. An empty exceptID deletes all of them.
+func RevokeUserSessions(ctx context.Context, userID any, exceptID string) (int, error) {
+	sessions, err := loadUserSessions(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	var revoked int
+	for id := range sessions {
+		if id == exceptID {
+			continue
+		}
+		if err := sessionStore.backend.Erase(ctx, id); err != nil {
+			return revoked, err
+		}
+		revoked++
+	}
+	return revoked, nil
+}
+
+// loadUserSessions returns the values of the live sessions of a user by their IDs.
+func loadUserSessions(ctx context.Context, userID any) (map[string]map[string]string, error) {
+	index, ok := sessionStore.backend.(UserSessionIndex)
+	if !ok {
+		return nil, ErrUserIndexNotSupported
+	}
+	user := lang.Repr(userID)
+	if user == "" {
+		// The anonymous sessions are not of a user.
+		return nil, nil
+	}
+	ids, err := index.UserSessions(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+
+	sessions := make(map[string]map[string]string, len(ids))
+	for _, id := range ids {
+		values, err := sessionStore.backend.Load(ctx, id)
+		if errors.Is(err, ErrSessionNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		// The session may have changed user since it is indexed.
+		if userIDOf(values) == user {
+			sessions[id] = values
+		}
+	}
+	return sessions, nil
+}
+
+// userIDOf returns the string form of the UserID value of encoded session values,
+// or an empty string if there is none.
+func userIDOf(values map[string]string) string {
+	encoded, ok := values[UserID]
+	if !ok {
+		return ""
+	}
+	var v any
+	if err := jsonx.UnmarshalFromString(encoded, &v); err != nil {
+		logx.Errorf("Invalid session value of %q: %s", UserID, encoded)
+		return ""
+	}
+	return lang.Repr(v)
+}
+
+func decodeString(encoded string) string {
+	var s string
+	_ = jsonx.UnmarshalFromString(encoded, &s)
+	return s
+}
+
+func parseTime(encoded string) time.Time {
+	t, _ := time.Parse(time.RFC3339, decodeString(encoded))
+	return t
+}
@@ -0,0 +1,107 @@
+package session
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUserSessions(t *testing.T) {
+	setupTestSessions(t)
+	handler := Middleware("pro")(func(w http.ResponseWriter, r *http.Request) {
+		s := From(r.Context())
+		if uid, err := strconv.ParseInt(r.URL.Query().Get("uid"), 10, 64); err == nil {
+			s.Set(UserID, uid)
+			s.Set(Authenticated, 1)
+		}
+	})
+	var ids []string
+	login := func(uid, userAgent string) {
+		r := httptest.NewRequest(http.MethodGet, "/login?uid="+uid, nil)
+		r.Header.Set("User-Agent", userAgent)
+		w := httptest.NewRecorder()
+		handler(w, r)
+		_, id := tokenID(t, w)
+		ids = append(ids, id)
+	}
+	login("1", "phone")
+	login("1", "laptop")
+	login("1", "tablet")
+	login("2", "other")
+	login("", "anonymous")
+
+	ctx := context.Background()
+	sessions, err := ListUserSessions(ctx, int64(1))
+	assert.NoError(t, err)
+	if assert.Len(t, sessions, 3) {
+		var userAgents []string
+		for _, s := range sessions {
+			userAgents = append(userAgents, s.UserAgent)
+			assert.Equal(t, "192.0.2.1", s.UserAddr)
+			assert.False(t, s.Created.IsZero())
+			assert.False(t, s.Updated.IsZero())
+		}
+		assert.ElementsMatch(t, []string{"phone", "laptop", "tablet"}, userAgents)
+	}
+	// The string form of the user ID finds the same sessions.
+	sessions, err = ListUserSessions(ctx, "1")
+	assert.NoError(t, err)
+	assert.Len(t, sessions, 3)
+
+	revoked, err := RevokeUserSessions(ctx, int64(1), ids[1])
+	assert.NoError(t, err)
+	assert.Equal(t, 2, revoked)
+	sessions, err = ListUserSessions(ctx, int64(1))
+	assert.NoError(t, err)
+	if assert.Len(t, sessions, 1) {
+		assert.Equal(t, ids[1], sessions[0].ID)
+	}
+	sessions, err = ListUserSessions(ctx, int64(2))
+	assert.NoError(t, err)
+	assert.Len(t, sessions, 1)
+
+	revoked, err = RevokeUserSessions(ctx, nil, "")
+	assert.NoError(t, err)
+	assert.Zero(t, revoked)
+}
+
+func TestRevokeDuringRequest(t *testing.T) {
+	setupTestSessions(t)
+	revoke := make(chan struct{})
+	revoked := make(chan struct{})
+	handler := Middleware("pro")(func(w http.ResponseWriter, r *http.Request) {
+		s := From(r.Context())
+		if r.URL.Path == "/login" {
+			s.Set(UserID, int64(1))
+			s.Set(Authenticated, 1)
+		}
+		if r.URL.Path == "/slow" {
+			close(revoke)
+			<-revoked
+		}
+	})
+	token, id := tokenID(t, request(handler, "/login", ""))
+
+	// The session is revoked while a request of it is in progress.
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		request(handler, "/slow", token)
+	}()
+	<-revoke
+	n, err := RevokeUserSessions(context.Background(), int64(1), "")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+	close(revoked)
+	<-done
+
+	_, err = sessionStore.backend.Load(context.Background(), id)
+	assert.ErrorIs(t, err, ErrSessionNotFound)
+	sessions, err := ListUserSessions(context.Background(), int64(1))
+	assert.NoError(t, err)
+	assert.Empty(t, sessions)
+}
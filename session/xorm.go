@@ -0,0 +1,163 @@
+package session
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/zeromicro/go-zero/core/jsonx"
+	"github.com/zeromicro/go-zero/core/logx"
+	"xorm.io/xorm"
+)
+
+var (
+	_ SessionStore     = (*sessionStoreXorm)(nil)
+	_ SessionUpdater   = (*sessionStoreXorm)(nil)
+	_ UserSessionIndex = (*sessionStoreXorm)(nil)
+)
+
+// xormSweepInterval is how often the expired sessions are deleted from the table of a sessionStoreXorm.
+const xormSweepInterval = time.Minute
+
+// sessionRow is a row of the table used by sessionStoreXorm.
+type sessionRow struct {
+	ID        string `xorm:"pk varchar(64) 'id'"`
+	UserID    string `xorm:"varchar(64) index 'user_id'"`
+	Data      string `xorm:"text notnull 'data'"`
+	ExpiresAt int64  `xorm:"notnull index 'expires_at'"`
+}
+
+// sessionStoreXorm stores the sessions in a SQL table.
+type sessionStoreXorm struct {
+	engine *xorm.Engine
+	table  string
+	now    func() time.Time
+
+	mu        sync.Mutex
+	lastSweep time.Time
+}
+
+// NewSessionStoreXorm returns a SessionStore which stores each session in a row of a SQL table,
+// with its values encoded as a JSON object, and its user in an indexed column.
+// The table is created if it does not exist.
+// The expired sessions read as absent, and are deleted by the next Save after a minute.
+func NewSessionStoreXorm(engine *xorm.Engine, table string) SessionStore {
+	err := engine.Table(table).Sync(new(sessionRow))
+	logx.Must(err)
+
+	return &sessionStoreXorm{
+		engine: engine,
+		table:  table,
+		now:    time.Now,
+	}
+}
+
+func (s *sessionStoreXorm) Load(ctx context.Context, id string) (map[string]string, error) {
+	var row sessionRow
+	ok, err := s.engine.Context(ctx).Table(s.table).ID(id).Get(&row)
+	if err != nil {
+		return nil, err
+	}
+	if !ok || row.ExpiresAt <= s.now().UnixMilli() {
+		return nil, ErrSessionNotFound
+	}
+	var values map[string]string
+	if err := jsonx.UnmarshalFromString(row.Data, &values); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+func (s *sessionStoreXorm) Save(ctx context.Context, id string, values map[string]string, ttl time.Duration) error {
+	now := s.now()
+	s.sweep(ctx, now)
+	if len(values) == 0 {
+		return s.Erase(ctx, id)
+	}
+
+	data, err := jsonx.MarshalToString(values)
+	if err != nil {
+		return err
+	}
+	row := &sessionRow{ID: id, UserID: userIDOf(values), Data: data, ExpiresAt: now.Add(ttl).UnixMilli()}
+	n, err := s.engine.Context(ctx).Table(s.table).ID(id).Cols("user_id", "data", "expires_at").Update(row)
+	if err != nil || n > 0 {
+		return err
+	}
+	if _, err = s.engine.Context(ctx).Table(s.table).Insert(row); err != nil {
+		// Another request has inserted the session in the meantime,
+		// or MySQL has not reported the unchanged row as affected.
+		if exists, existsErr := s.engine.Context(ctx).Table(s.table).ID(id).Exist(new(sessionRow)); existsErr == nil && exists {
+			_, err = s.engine.Context(ctx).Table(s.table).ID(id).Cols("user_id", "data", "expires_at").Update(row)
+		}
+	}
+	return err
+}
+
+func (s *sessionStoreXorm) Update(ctx context.Context, id string, values map[string]string, ttl time.Duration) error {
+	if len(values) == 0 {
+		return s.Erase(ctx, id)
+	}
+
+	data, err := jsonx.MarshalToString(values)
+	if err != nil {
+		return err
+	}
+	now := s.now()
+	row := &sessionRow{UserID: userIDOf(values), Data: data, ExpiresAt: now.Add(ttl).UnixMilli()}
+	live := s.engine.Quote("expires_at") + " > ?"
+	n, err := s.engine.Context(ctx).Table(s.table).ID(id).Where(live, now.UnixMilli()).
+		Cols("user_id", "data", "expires_at").Update(row)
+	if err != nil || n > 0 {
+		return err
+	}
+	// MySQL does not report the unchanged row as affected.
+	exists, err := s.engine.Context(ctx).Table(s.table).ID(id).Where(live, now.UnixMilli()).Exist(new(sessionRow))
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return ErrSessionNotFound
+	}
+	return nil
+}
+
+func (s *sessionStoreXorm) Erase(ctx context.Context, id string) error {
+	_, err := s.engine.Context(ctx).Table(s.table).ID(id).Delete(new(sessionRow))
+	return err
+}
+
+func (s *sessionStoreXorm) Touch(ctx context.Context, id string, ttl time.Duration) error {
+	now := s.now()
+	_, err := s.engine.Context(ctx).Table(s.table).ID(id).
+		Where(s.engine.Quote("expires_at")+" > ?", now.UnixMilli()).
+		Cols("expires_at").Update(&sessionRow{ExpiresAt: now.Add(ttl).UnixMilli()})
+	return err
+}
+
+func (s *sessionStoreXorm) UserSessions(ctx context.Context, userID string) ([]string, error) {
+	var ids []string
+	err := s.engine.Context(ctx).Table(s.table).Cols("id").
+		Where(s.engine.Quote("user_id")+" = ?", userID).
+		And(s.engine.Quote("expires_at")+" > ?", s.now().UnixMilli()).
+		Find(&ids)
+	return ids, err
+}
+
+// sweep deletes the expired sessions, at most once per xormSweepInterval.
+func (s *sessionStoreXorm) sweep(ctx context.Context, now time.Time) {
+	s.mu.Lock()
+	if now.Sub(s.lastSweep) < xormSweepInterval {
+		s.mu.Unlock()
+		return
+	}
+	s.lastSweep = now
+	s.mu.Unlock()
+
+	_, err := s.engine.Context(ctx).Table(s.table).
+		Where(s.engine.Quote("expires_at")+" <= ?", now.UnixMilli()).
+		Delete(new(sessionRow))
+	if err != nil {
+		logx.Errorf("Can not delete the expired sessions: %v", err)
+	}
+}